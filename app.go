@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"sync"
 
+	"github.com/markusylisiurunen/juttele/internal/logger"
 	"github.com/markusylisiurunen/juttele/internal/middleware"
 	"github.com/markusylisiurunen/juttele/internal/repo"
 	_ "github.com/mattn/go-sqlite3"
@@ -18,11 +20,16 @@ type App struct {
 	configSmallButCapableModel string
 
 	// runtime state
-	db     *sql.DB
-	repo   *repo.Repository
-	router *http.ServeMux
-	models []Model
-	tools  []Tool
+	db          *sql.DB
+	repo        *repo.Repository
+	router      *http.ServeMux
+	hub         *eventHub
+	models      []Model
+	tools       []Tool
+	toolCatalog *ToolCatalog
+	jobs        map[int64]*generationJob
+	jobsMu      sync.Mutex
+	search      bool
 }
 
 type appOption func(*App)
@@ -56,8 +63,10 @@ func New(token string, opts ...appOption) *App {
 	app.configDataFolder = "./.data"
 	app.configToken = token
 	app.router = http.NewServeMux()
+	app.hub = newEventHub()
 	app.models = make([]Model, 0)
 	app.tools = make([]Tool, 0)
+	app.jobs = make(map[int64]*generationJob)
 	for _, opt := range opts {
 		opt(app)
 	}
@@ -68,6 +77,7 @@ func (app *App) ListenAndServe(ctx context.Context) error {
 	type initFunc = func(ctx context.Context) error
 	initFuncs := []initFunc{
 		app.initModels,
+		app.initTools,
 		app.initDatabase,
 		app.initRoutes,
 	}
@@ -92,6 +102,15 @@ func (app *App) ListenAndServe(ctx context.Context) error {
 	return err
 }
 
+// Close closes the database opened by ImportChats. ListenAndServe closes it on its own once its
+// context is done.
+func (app *App) Close() error {
+	if app.db == nil {
+		return nil
+	}
+	return app.db.Close()
+}
+
 // ---
 
 func (app *App) getSmallButCapableModel() Model {
@@ -118,6 +137,22 @@ func (app *App) initModels(ctx context.Context) error {
 	return nil
 }
 
+// initTools builds the catalog which generations with tools start from, validating it against every model.
+func (app *App) initTools(ctx context.Context) error {
+	app.toolCatalog = NewToolCatalog()
+	for _, tool := range app.tools {
+		if err := app.toolCatalog.Register(tool); err != nil {
+			return err
+		}
+		for _, model := range app.models {
+			if err := validateToolSpec(model, tool); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (app *App) initDatabase(ctx context.Context) error {
 	client, err := sql.Open("sqlite3",
 		fmt.Sprintf("file:%s/juttele.db?_fk=1", app.configDataFolder))
@@ -129,6 +164,14 @@ func (app *App) initDatabase(ctx context.Context) error {
 		return err
 	}
 	app.repo = repo.New(client)
+	search, err := app.repo.SetupSearch(ctx)
+	if err != nil {
+		return err
+	}
+	if !search {
+		logger.Get().Error("search is disabled, as SQLite was built without FTS5 (see the sqlite_fts5 build tag)")
+	}
+	app.search = search
 	return nil
 }
 
@@ -144,8 +187,19 @@ func (app *App) initRoutes(ctx context.Context) error {
 		{"GET /config", app.configRouteHandler},
 		{"GET /data", app.dataRouteHandler},
 		{"POST /rpc", app.rpcRouteHandler},
+		{"GET /usage", app.usageRouteHandler},
+		{"GET /chats", app.chatsRouteHandler},
+		{"GET /chats/{id}/blocks", app.chatBlocksRouteHandler},
+		{"GET /chats/{id}/export", app.chatExportRouteHandler},
+		{"POST /chats/import", app.chatImportRouteHandler},
+		{"GET /events", app.eventsRouteHandler},
+		{"POST /attachments", app.uploadAttachmentRouteHandler},
+		{"GET /attachments/{id}", app.attachmentRouteHandler},
 		{"GET /chats/{id}", app.sendRouteHandler},
 	}
+	if app.search {
+		mountables = append(mountables, mountable{"GET /search", app.searchRouteHandler})
+	}
 	for _, i := range mountables {
 		app.router.Handle(i.pattern,
 			middleware.Log()(
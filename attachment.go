@@ -0,0 +1,115 @@
+package juttele
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+type Modality string
+
+const (
+	ModalityText     Modality = "text"
+	ModalityImage    Modality = "image"
+	ModalityDocument Modality = "document"
+)
+
+// Attachment is a file attached to a user message. Its data is loaded from the data folder only for
+// the duration of a generation and never persisted with the message.
+type Attachment struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"-"`
+}
+
+// attachmentModality maps the media type onto the modality a model needs to support to take it as
+// input. Text files are inlined into the message, so every model supports them.
+func attachmentModality(mediaType string) (Modality, bool) {
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return ModalityImage, true
+	case "application/pdf":
+		return ModalityDocument, true
+	case "application/json", "application/xml", "application/x-yaml", "application/yaml":
+		return ModalityText, true
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return ModalityText, true
+	}
+	return "", false
+}
+
+func (a Attachment) Modality() Modality {
+	modality, _ := attachmentModality(a.MediaType)
+	return modality
+}
+
+func (a Attachment) base64() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+func (a Attachment) dataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", a.MediaType, a.base64())
+}
+
+// text renders a text attachment for inlining into the message.
+func (a Attachment) text() string {
+	return fmt.Sprintf("<attachment name=%q>\n%s\n</attachment>", a.Name, a.Data)
+}
+
+// normalizeMediaType strips the parameters from the media type, e.g. the charset of text files.
+func normalizeMediaType(mediaType string) string {
+	v, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return ""
+	}
+	return v
+}
+
+// ---
+
+func (app *App) attachmentPath(id string) string {
+	return filepath.Join(app.configDataFolder, "attachments", id)
+}
+
+func (app *App) getAttachment(ctx context.Context, id string) (Attachment, error) {
+	v, err := app.repo.GetAttachment(ctx, repo.GetAttachmentArgs{UUID: id})
+	if err != nil {
+		return Attachment{}, fmt.Errorf("error getting attachment %q: %w", id, err)
+	}
+	return Attachment{ID: v.UUID, Name: v.Name, MediaType: v.MediaType}, nil
+}
+
+// loadAttachments loads the data of the attachments in the history. Attachments the model does not
+// support, e.g. images in a chat continued with a text-only model, are replaced by a note in the text.
+func (app *App) loadAttachments(history []Message, modelInfo ModelInfo) error {
+	for _, i := range history {
+		msg, ok := i.(*UserMessage)
+		if !ok || len(msg.Attachments) == 0 {
+			continue
+		}
+		attachments := make([]Attachment, 0, len(msg.Attachments))
+		for _, j := range msg.Attachments {
+			if !modelInfo.Supports(j.Modality()) {
+				msg.Content += fmt.Sprintf("\n\n(The attachment %q was omitted, as you do not support %s input.)",
+					j.Name, j.Modality())
+				continue
+			}
+			data, err := os.ReadFile(app.attachmentPath(j.ID))
+			if err != nil {
+				return fmt.Errorf("error reading attachment %q: %w", j.ID, err)
+			}
+			j.Data = data
+			attachments = append(attachments, j)
+		}
+		msg.Attachments = attachments
+	}
+	return nil
+}
@@ -13,10 +13,12 @@ import (
 type BlockType string
 
 const (
-	BlockTypeThinking BlockType = "thinking"
-	BlockTypeText     BlockType = "text"
-	BlockTypeTool     BlockType = "tool"
-	BlockTypeError    BlockType = "error"
+	BlockTypeThinking   BlockType = "thinking"
+	BlockTypeText       BlockType = "text"
+	BlockTypeTool       BlockType = "tool"
+	BlockTypeError      BlockType = "error"
+	BlockTypeNotice     BlockType = "notice"
+	BlockTypeAttachment BlockType = "attachment"
 )
 
 type Block interface {
@@ -94,6 +96,7 @@ func (b *ThinkingBlock) MarshalJSON() ([]byte, error) {
 type TextBlock struct {
 	BaseBlock
 	Role    string `json:"role"`
+	Model   string `json:"model,omitempty"`
 	Content string `json:"content"`
 }
 
@@ -124,6 +127,7 @@ func (b *TextBlock) MarshalJSON() ([]byte, error) {
 
 type ToolBlock struct {
 	BaseBlock
+	CallID string  `json:"call_id,omitempty"`
 	Name   string  `json:"name"`
 	Args   string  `json:"args"`
 	Result *string `json:"result,omitempty"`
@@ -216,6 +220,58 @@ func (b *ErrorBlock) MarshalJSON() ([]byte, error) {
 	return json.Marshal((*Alias)(b))
 }
 
+type NoticeBlock struct {
+	BaseBlock
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewNoticeBlock(code, message string) *NoticeBlock {
+	b := &NoticeBlock{
+		BaseBlock: newBaseBlock(BlockTypeNotice, ""),
+		Code:      code,
+		Message:   message,
+	}
+	b.calculateHash()
+	return b
+}
+
+func (b *NoticeBlock) calculateHash() {
+	b.Hash = calculateBlockHash(b.Code + b.Message)
+}
+
+func (b *NoticeBlock) MarshalJSON() ([]byte, error) {
+	type Alias NoticeBlock
+	return json.Marshal((*Alias)(b))
+}
+
+type AttachmentBlock struct {
+	BaseBlock
+	AttachmentID string `json:"attachment_id"`
+	Name         string `json:"name"`
+	MediaType    string `json:"media_type"`
+}
+
+func NewAttachmentBlock(attachment Attachment) *AttachmentBlock {
+	b := &AttachmentBlock{
+		BaseBlock:    newBaseBlock(BlockTypeAttachment, ""),
+		AttachmentID: attachment.ID,
+		Name:         attachment.Name,
+		MediaType:    attachment.MediaType,
+	}
+	b.calculateHash()
+	return b
+}
+
+func (b *AttachmentBlock) calculateHash() {
+	b.Hash = calculateBlockHash(b.AttachmentID + b.Name + b.MediaType)
+}
+
+func (b *AttachmentBlock) MarshalJSON() ([]byte, error) {
+	type Alias AttachmentBlock
+	return json.Marshal((*Alias)(b))
+}
+
 func calculateBlockHash(content string) string {
 	h := xxhash.New()
 	util.Must(h.WriteString(content))
@@ -254,6 +310,18 @@ func parseBlock(data []byte) (Block, error) {
 			return nil, err
 		}
 		return &block, nil
+	case BlockTypeNotice:
+		var block NoticeBlock
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	case BlockTypeAttachment:
+		var block AttachmentBlock
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
 	default:
 		return nil, fmt.Errorf("unknown block type: %q", baseBlock.Type)
 	}
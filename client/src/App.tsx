@@ -1,12 +1,13 @@
 import "./styles/globals.css";
 
-import React, { useRef, useState } from "react";
+import { Archive, ArchiveRestore, Pin, PinOff, Trash } from "lucide-react";
+import React, { useEffect, useRef, useState } from "react";
 import { DataResponse } from "./api";
 import styles from "./App.module.css";
 import { AnyBlock } from "./blocks";
 import { ChatHistory, Header, MessageBox } from "./components";
 import { AppProvider } from "./contexts";
-import { useApp, useMountOnce } from "./hooks";
+import { useApp, useMount, useMountOnce } from "./hooks";
 import {
   makeEditFileTool,
   makeGrepTool,
@@ -14,7 +15,15 @@ import {
   makeReadFileTool,
   makeWriteFileTool,
 } from "./tools";
-import { assertNever, Atom, streamCompletion, useAtomWithSelector } from "./utils";
+import {
+  assertNever,
+  Atom,
+  ChatEvent,
+  CompletionRequest,
+  streamCompletion,
+  subscribeEvents,
+  useAtomWithSelector,
+} from "./utils";
 
 const BASE_URL = import.meta.env.VITE_API_BASE_URL;
 const API_KEY = import.meta.env.VITE_API_KEY;
@@ -34,6 +43,51 @@ function upsertBlock(dataAtom: Atom<DataResponse>, chatId: number, block: AnyBlo
   });
 }
 
+function applyChatEvent(dataAtom: Atom<DataResponse>, event: ChatEvent) {
+  switch (event.method) {
+    case "chat_created":
+    case "chat_updated": {
+      const { updated_at: _, ...params } = event.params;
+      dataAtom.set((data) => {
+        const chat = data.chats.find((chat) => chat.id === params.id);
+        if (!chat) {
+          return { ...data, chats: [{ ...params, generating: false, blocks: [] }, ...data.chats] };
+        }
+        return {
+          ...data,
+          chats: data.chats.map((i) => (i.id === params.id ? { ...i, ...params } : i)),
+        };
+      });
+      return;
+    }
+    case "chat_deleted":
+      dataAtom.set((data) => ({
+        ...data,
+        chats: data.chats.filter((chat) => chat.id !== event.params.id),
+      }));
+      return;
+    case "block":
+      upsertBlock(dataAtom, event.params.chat_id, event.params.block);
+      return;
+    default:
+      assertNever(event);
+  }
+}
+
+function truncateBlocks(dataAtom: Atom<DataResponse>, chatId: number, blockId: string) {
+  dataAtom.set((data) => {
+    return {
+      ...data,
+      chats: data.chats.map((chat) => {
+        if (chat.id !== chatId) return chat;
+        const idx = chat.blocks.findIndex((i) => i.id === blockId);
+        if (idx === -1) return chat;
+        return { ...chat, blocks: chat.blocks.slice(0, idx) };
+      }),
+    };
+  });
+}
+
 //--------------------------------------------------------------------------------------------------
 
 type AppProps = {
@@ -44,17 +98,30 @@ type AppProps = {
 const App: React.FC<AppProps> = ({ chatId, onShowChats, onReset }) => {
   const app = useApp();
   const scrollRef = useRef<HTMLDivElement>(null);
-  function onMessage(message: string) {
+  function generate(request: CompletionRequest) {
     const { modelId, personalityId, tools, think } = app.generation.get();
     void Promise.resolve().then(async () => {
-      upsertBlock(app.data, chatId, {
-        id: Date.now().toString(),
-        ts: new Date().toISOString(),
-        hash: "",
-        type: "text",
-        role: "user",
-        content: message,
-      });
+      if (request.method === "generate") {
+        upsertBlock(app.data, chatId, {
+          id: Date.now().toString(),
+          ts: new Date().toISOString(),
+          hash: "",
+          type: "text",
+          role: "user",
+          content: request.content,
+        });
+        for (const attachment of request.attachments ?? []) {
+          upsertBlock(app.data, chatId, {
+            id: `${Date.now()}-${attachment.id}`,
+            ts: new Date().toISOString(),
+            hash: "",
+            type: "attachment",
+            attachment_id: attachment.id,
+            name: attachment.name,
+            media_type: attachment.media_type,
+          });
+        }
+      }
       requestAnimationFrame(() => {
         scrollRef.current?.scrollTo({ top: 1_000_000, behavior: "smooth" });
       });
@@ -70,7 +137,7 @@ const App: React.FC<AppProps> = ({ chatId, onShowChats, onReset }) => {
           personalityId,
           tools,
           think,
-          message,
+          request,
           [
             ...(baseFileSystemPath
               ? [
@@ -86,22 +153,41 @@ const App: React.FC<AppProps> = ({ chatId, onShowChats, onReset }) => {
             if (msg.method === "block") {
               upsertBlock(app.data, chatId, msg.params);
             }
+            if (msg.method === "truncate") {
+              truncateBlocks(app.data, chatId, msg.params.block_id);
+            }
+          },
+          (notify) => {
+            app.generation.set((state) => ({
+              ...state,
+              cancelToolCall: (callId) => notify("cancel_tool_call", { call_id: callId }),
+              stop: () => notify("stop", {}),
+            }));
           }
         );
       } catch (error) {
         console.error(error);
       } finally {
-        app.generation.set((state) => ({ ...state, generating: false }));
+        app.generation.set((state) => ({ ...state, generating: false, cancelToolCall: null, stop: null }));
       }
       // re-fetch the chat's data
       const data = await app.api.getData();
       app.data.set(data);
+      // generations keep running on the server when the connection drops, so re-attach to them
+      if (data.chats.find((chat) => chat.id === chatId)?.generating) {
+        setTimeout(() => generate({ method: "attach" }), 1_000);
+      }
     });
   }
+  useEffect(() => {
+    const chat = app.data.get().chats.find((chat) => chat.id === chatId);
+    if (chat?.generating && !app.generation.get().generating) {
+      generate({ method: "attach" });
+    }
+  }, [chatId]);
   function onRenameChatClick() {
     void Promise.resolve().then(async () => {
-      const { modelId } = app.generation.get();
-      await app.api.rpc("rename_chat", { id: chatId, model_id: modelId });
+      await app.api.rpc("rename_chat", { id: chatId, auto: true });
       const data = await app.api.getData();
       app.data.set(data);
     });
@@ -115,10 +201,17 @@ const App: React.FC<AppProps> = ({ chatId, onShowChats, onReset }) => {
         onShowChats={onShowChats}
       />
       <div className={styles.content}>
-        <ChatHistory chatId={chatId} scrollRef={scrollRef} />
+        <ChatHistory
+          chatId={chatId}
+          scrollRef={scrollRef}
+          onRegenerate={(blockId) => generate({ method: "regenerate", eventId: blockId })}
+          onEdit={(blockId, content) => generate({ method: "edit", eventId: blockId, content })}
+        />
         <MessageBox
-          onSend={(message) => onMessage(message)}
-          onCancel={() => {} /* TODO: cancel the generation */}
+          onSend={(message, attachments) =>
+            generate({ method: "generate", content: message, attachments })
+          }
+          onCancel={() => app.generation.get().stop?.()}
         />
       </div>
     </div>
@@ -132,18 +225,59 @@ type ChatsProps = {
 };
 const Chats: React.FC<ChatsProps> = ({ onGoToApp }) => {
   const app = useApp();
+  const [showArchived, setShowArchived] = useState(false);
   const chats = useAtomWithSelector(app.data, (data) => {
     return data.chats
-      .toSorted((a, b) => new Date(b.ts).getTime() - new Date(a.ts).getTime())
-      .map((chat) => ({ id: chat.id, title: chat.title }));
+      .filter((chat) => chat.archived === showArchived)
+      .toSorted((a, b) => {
+        if (a.pinned !== b.pinned) return a.pinned ? -1 : 1;
+        return new Date(b.ts).getTime() - new Date(a.ts).getTime();
+      })
+      .map((chat) => ({
+        id: chat.id,
+        title: chat.title,
+        pinned: chat.pinned,
+        archived: chat.archived,
+      }));
   });
+  function onChatOp(op: string, chatId: number) {
+    void Promise.resolve().then(async () => {
+      await app.api.rpc(op, { id: chatId });
+      const data = await app.api.getData();
+      app.data.set(data);
+    });
+  }
+  function onDelete(chatId: number) {
+    if (!window.confirm("Delete this chat permanently?")) return;
+    onChatOp("delete_chat", chatId);
+  }
   return (
     <div className={styles.chats}>
       <div className={styles.content}>
+        <button className={styles.toggle} onClick={() => setShowArchived((v) => !v)}>
+          <span>{showArchived ? "Show active chats" : "Show archived chats"}</span>
+        </button>
         {chats.map((chat) => (
-          <button key={chat.id} onClick={() => onGoToApp(chat.id)}>
-            <span>{chat.title}</span>
-          </button>
+          <div key={chat.id} className={styles.chat}>
+            <button className={styles.title} onClick={() => onGoToApp(chat.id)}>
+              <span>{chat.title}</span>
+            </button>
+            <button
+              title={chat.pinned ? "Unpin" : "Pin"}
+              onClick={() => onChatOp(chat.pinned ? "unpin_chat" : "pin_chat", chat.id)}
+            >
+              {chat.pinned ? <PinOff size={14} /> : <Pin size={14} />}
+            </button>
+            <button
+              title={chat.archived ? "Unarchive" : "Archive"}
+              onClick={() => onChatOp(chat.archived ? "unarchive_chat" : "archive_chat", chat.id)}
+            >
+              {chat.archived ? <ArchiveRestore size={14} /> : <Archive size={14} />}
+            </button>
+            <button title="Delete" onClick={() => onDelete(chat.id)}>
+              <Trash size={14} />
+            </button>
+          </div>
         ))}
       </div>
     </div>
@@ -167,6 +301,21 @@ const AppWrapper: React.FC = () => {
     app.data.set(data);
   }
   useMountOnce(() => void init());
+  const chatIdRef = useRef(chatId);
+  chatIdRef.current = chatId;
+  useMount(() => {
+    return subscribeEvents(
+      BASE_URL,
+      API_KEY,
+      (event) => {
+        // the blocks of this client's own generation already arrive over its generation's connection
+        const own = event.method === "block" && event.params.chat_id === chatIdRef.current;
+        if (own && app.generation.get().generating) return;
+        applyChatEvent(app.data, event);
+      },
+      () => void app.api.getData().then((data) => app.data.set(data))
+    );
+  });
   if (!chatId) {
     return null;
   }
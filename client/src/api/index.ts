@@ -23,12 +23,41 @@ const DataResponse = z.object({
       id: z.number(),
       ts: z.string().datetime(),
       title: z.string(),
+      pinned: z.boolean(),
+      archived: z.boolean(),
+      forked_from: z.number().optional(),
+      generating: z.boolean(),
       blocks: z.array(AnyBlock),
     })
   ),
 });
 type DataResponse = z.infer<typeof DataResponse>;
 
+const ChatsResponse = z.object({
+  chats: z.array(
+    z.object({
+      id: z.number(),
+      ts: z.string().datetime(),
+      updated_at: z.string().datetime(),
+      title: z.string(),
+      pinned: z.boolean(),
+      archived: z.boolean(),
+      forked_from: z.number().optional(),
+    })
+  ),
+  deleted: z.array(z.number()).optional(),
+  next_cursor: z.string().optional(),
+  since: z.string().datetime(),
+});
+type ChatsResponse = z.infer<typeof ChatsResponse>;
+
+const ChatBlocksResponse = z.object({
+  ids: z.array(z.string()),
+  blocks: z.array(AnyBlock),
+  since: z.string().datetime(),
+});
+type ChatBlocksResponse = z.infer<typeof ChatBlocksResponse>;
+
 function makeGetConfig(baseUrl: string, token: string) {
   return async (): Promise<ConfigResponse> => {
     const resp = await fetch(`${baseUrl}/config`, {
@@ -57,6 +86,68 @@ function makeGetData(baseUrl: string, token: string) {
   };
 }
 
+function makeGetChats(baseUrl: string, token: string) {
+  return async (params: { cursor?: string; since?: string; limit?: number } = {}): Promise<ChatsResponse> => {
+    const query = new URLSearchParams();
+    if (params.cursor) query.set("cursor", params.cursor);
+    if (params.since) query.set("since", params.since);
+    if (params.limit) query.set("limit", String(params.limit));
+    const resp = await fetch(`${baseUrl}/chats?${query.toString()}`, {
+      method: "GET",
+      headers: {
+        Accept: "application/json",
+        Authorization: `Bearer ${token}`,
+      },
+    });
+    const data = await resp.json();
+    return ChatsResponse.parse(data);
+  };
+}
+
+function makeGetChatBlocks(baseUrl: string, token: string) {
+  return async (chatId: number, since?: string): Promise<ChatBlocksResponse> => {
+    const query = new URLSearchParams();
+    if (since) query.set("since", since);
+    const resp = await fetch(`${baseUrl}/chats/${chatId}/blocks?${query.toString()}`, {
+      method: "GET",
+      headers: {
+        Accept: "application/json",
+        Authorization: `Bearer ${token}`,
+      },
+    });
+    const data = await resp.json();
+    return ChatBlocksResponse.parse(data);
+  };
+}
+
+const UploadAttachmentResponse = z.object({
+  id: z.string(),
+  name: z.string(),
+  media_type: z.string(),
+  size: z.number(),
+});
+type UploadAttachmentResponse = z.infer<typeof UploadAttachmentResponse>;
+
+function makeUploadAttachment(baseUrl: string, token: string) {
+  return async (file: File, modelId?: string): Promise<UploadAttachmentResponse> => {
+    const query = new URLSearchParams();
+    if (modelId) query.set("model_id", modelId);
+    const body = new FormData();
+    body.set("file", file);
+    const resp = await fetch(`${baseUrl}/attachments?${query.toString()}`, {
+      method: "POST",
+      headers: {
+        Accept: "application/json",
+        Authorization: `Bearer ${token}`,
+      },
+      body: body,
+    });
+    if (!resp.ok) throw new Error(await resp.text());
+    const data = await resp.json();
+    return UploadAttachmentResponse.parse(data);
+  };
+}
+
 function makeRpc(baseUrl: string, token: string) {
   return async (op: string, args: Record<string, unknown>): Promise<unknown> => {
     const resp = await fetch(`${baseUrl}/rpc`, {
@@ -84,9 +175,32 @@ class API {
     return makeGetData(this.baseUrl, this.token)();
   }
 
+  async getChats(params?: { cursor?: string; since?: string; limit?: number }) {
+    return makeGetChats(this.baseUrl, this.token)(params);
+  }
+
+  async getChatBlocks(chatId: number, since?: string) {
+    return makeGetChatBlocks(this.baseUrl, this.token)(chatId, since);
+  }
+
+  async uploadAttachment(file: File, modelId?: string) {
+    return makeUploadAttachment(this.baseUrl, this.token)(file, modelId);
+  }
+
+  attachmentUrl(attachmentId: string) {
+    return `${this.baseUrl}/attachments/${attachmentId}?api_key=${encodeURIComponent(this.token)}`;
+  }
+
   async rpc(op: string, args: Record<string, unknown>) {
     return makeRpc(this.baseUrl, this.token)(op, args);
   }
 }
 
-export { API, ConfigResponse, DataResponse };
+export {
+  API,
+  ChatBlocksResponse,
+  ChatsResponse,
+  ConfigResponse,
+  DataResponse,
+  UploadAttachmentResponse,
+};
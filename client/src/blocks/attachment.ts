@@ -0,0 +1,14 @@
+import { z } from "zod";
+
+const AttachmentBlock = z.object({
+  id: z.string(),
+  ts: z.string().datetime(),
+  hash: z.string(),
+  type: z.literal("attachment"),
+  attachment_id: z.string(),
+  name: z.string(),
+  media_type: z.string(),
+});
+type AttachmentBlock = z.infer<typeof AttachmentBlock>;
+
+export { AttachmentBlock };
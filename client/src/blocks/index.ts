@@ -1,10 +1,19 @@
 import { z } from "zod";
+import { AttachmentBlock } from "./attachment";
 import { ErrorBlock } from "./error";
+import { NoticeBlock } from "./notice";
 import { TextBlock } from "./text";
 import { ThinkingBlock } from "./thinking";
 import { ToolBlock } from "./tool";
 
-const AnyBlock = z.union([ErrorBlock, TextBlock, ToolBlock, ThinkingBlock]);
+const AnyBlock = z.union([
+  AttachmentBlock,
+  ErrorBlock,
+  NoticeBlock,
+  TextBlock,
+  ToolBlock,
+  ThinkingBlock,
+]);
 type AnyBlock = z.infer<typeof AnyBlock>;
 
-export { AnyBlock, ErrorBlock, TextBlock, ThinkingBlock, ToolBlock };
+export { AnyBlock, AttachmentBlock, ErrorBlock, NoticeBlock, TextBlock, ThinkingBlock, ToolBlock };
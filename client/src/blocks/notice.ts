@@ -0,0 +1,13 @@
+import { z } from "zod";
+
+const NoticeBlock = z.object({
+  id: z.string(),
+  ts: z.string().datetime(),
+  hash: z.string(),
+  type: z.literal("notice"),
+  code: z.string(),
+  message: z.string(),
+});
+type NoticeBlock = z.infer<typeof NoticeBlock>;
+
+export { NoticeBlock };
@@ -6,6 +6,7 @@ const TextBlock = z.object({
   hash: z.string(),
   type: z.literal("text"),
   role: z.union([z.literal("user"), z.literal("assistant")]),
+  model: z.string().optional(),
   content: z.string(),
 });
 type TextBlock = z.infer<typeof TextBlock>;
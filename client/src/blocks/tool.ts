@@ -5,6 +5,7 @@ const ToolBlock = z.object({
   ts: z.string().datetime(),
   hash: z.string(),
   type: z.literal("tool"),
+  call_id: z.string().optional(),
   name: z.string(),
   args: z.string(),
   result: z.string().optional(),
@@ -0,0 +1,34 @@
+import { FileText } from "lucide-react";
+import React from "react";
+import { AttachmentBlock } from "../../blocks";
+import { useApp } from "../../hooks";
+import styles from "./AttachmentBlock.module.css";
+
+type AttachmentComponentProps = {
+  block: AttachmentBlock;
+};
+const AttachmentComponent: React.FC<AttachmentComponentProps> = ({ block }) => {
+  const app = useApp();
+  const url = app.api.attachmentUrl(block.attachment_id);
+  return (
+    <div className={styles.root} data-block="attachment">
+      <a href={url} target="_blank" rel="noreferrer">
+        {block.media_type.startsWith("image/") ? (
+          <img src={url} alt={block.name} />
+        ) : (
+          <span>
+            <FileText size={13} strokeWidth={2.5} />
+            {block.name}
+          </span>
+        )}
+      </a>
+    </div>
+  );
+};
+const MemoedAttachmentComponent = React.memo(AttachmentComponent, (prev, next) => {
+  if (prev.block.id !== next.block.id) return false;
+  if (prev.block.hash !== next.block.hash) return false;
+  return true;
+});
+
+export { MemoedAttachmentComponent as Attachment };
@@ -1,8 +1,10 @@
+import { Attachment } from "./AttachmentBlock";
 import { Error } from "./ErrorBlock";
+import { Notice } from "./NoticeBlock";
 import { Text } from "./TextBlock";
 import { Thinking } from "./ThinkingBlock";
 import { Tool } from "./ToolBlock";
 
-const Block = { Error, Text, Thinking, Tool };
+const Block = { Attachment, Error, Notice, Text, Thinking, Tool };
 
 export { Block };
@@ -0,0 +1,21 @@
+import React from "react";
+import { NoticeBlock } from "../../blocks";
+import styles from "./NoticeBlock.module.css";
+
+type NoticeComponentProps = {
+  block: NoticeBlock;
+};
+const NoticeComponent: React.FC<NoticeComponentProps> = ({ block }) => {
+  return (
+    <div className={styles.root} data-block="notice" data-code={block.code}>
+      <span>{block.message}</span>
+    </div>
+  );
+};
+const MemoedNoticeComponent = React.memo(NoticeComponent, (prev, next) => {
+  if (prev.block.id !== next.block.id) return false;
+  if (prev.block.hash !== next.block.hash) return false;
+  return true;
+});
+
+export { MemoedNoticeComponent as Notice };
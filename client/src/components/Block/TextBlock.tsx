@@ -1,9 +1,17 @@
-import { CopyIcon, TrashIcon } from "lucide-react";
-import React from "react";
+import {
+  ChevronLeftIcon,
+  ChevronRightIcon,
+  CopyIcon,
+  PencilIcon,
+  RefreshCwIcon,
+  TrashIcon,
+} from "lucide-react";
+import React, { useEffect, useState } from "react";
 import Markdown from "react-markdown";
 import rehypeKatex from "rehype-katex";
 import remarkGfm from "remark-gfm";
 import remarkMath from "remark-math";
+import { z } from "zod";
 import { TextBlock } from "../../blocks";
 import { useApp } from "../../hooks";
 import { Pre } from "./Markdown/Pre";
@@ -16,17 +24,64 @@ function preprocess(content: string) {
   return content;
 }
 
+const SiblingsResponse = z.object({
+  items: z.array(
+    z.object({
+      id: z.string(),
+      block_id: z.string(),
+      ts: z.string().datetime(),
+      active: z.boolean(),
+    })
+  ),
+});
+type SiblingsResponse = z.infer<typeof SiblingsResponse>;
+
+function useSiblings(chatId: number | undefined, block: TextBlock) {
+  const app = useApp();
+  const [siblings, setSiblings] = useState<SiblingsResponse["items"]>([]);
+  useEffect(() => {
+    if (chatId === undefined || block.role !== "user") return;
+    app.api
+      .rpc("list_chat_event_siblings", { chat_id: chatId, id: block.id })
+      .then((data) => {
+        const parsed = SiblingsResponse.safeParse(data);
+        if (parsed.success) setSiblings(parsed.data.items);
+      })
+      .catch(() => setSiblings([]));
+  }, [app, chatId, block.id, block.role]);
+  return siblings;
+}
+
 type TextComponentProps = {
   block: TextBlock;
+  chatId?: number;
+  onRegenerate?: (blockId: string) => void;
+  onEdit?: (blockId: string, content: string) => void;
 };
-const TextComponent: React.FC<TextComponentProps> = ({ block }) => {
+const TextComponent: React.FC<TextComponentProps> = ({ block, chatId, onRegenerate, onEdit }) => {
   const app = useApp();
+  const siblings = useSiblings(chatId, block);
+  const siblingIdx = siblings.findIndex((i) => i.block_id === block.id);
+  function onSwitchBranch(idx: number) {
+    const sibling = siblings[idx];
+    if (!sibling || chatId === undefined) return;
+    void Promise.resolve().then(async () => {
+      await app.api.rpc("switch_chat_branch", { chat_id: chatId, id: sibling.id });
+      const data = await app.api.getData();
+      app.data.set(data);
+    });
+  }
   function onCopy() {
     navigator.clipboard.writeText(block.content.trim() + "\n");
   }
+  function onEditClick() {
+    const content = window.prompt("Edit message", block.content);
+    if (content === null || content.trim() === "") return;
+    onEdit?.(block.id, content);
+  }
   function onDelete() {
     void Promise.resolve().then(async () => {
-      await app.api.rpc("delete_chat_event", { id: block.id });
+      await app.api.rpc("delete_chat_event", { chat_id: chatId, id: block.id });
       const data = await app.api.getData();
       app.data.set(data);
     });
@@ -44,14 +99,43 @@ const TextComponent: React.FC<TextComponentProps> = ({ block }) => {
       </div>
       {block.role === "assistant" || block.role === "user" ? (
         <div className={styles.actions}>
+          {siblings.length > 1 && siblingIdx !== -1 ? (
+            <>
+              <button disabled={siblingIdx === 0} onClick={() => onSwitchBranch(siblingIdx - 1)}>
+                <ChevronLeftIcon size={13} />
+              </button>
+              <span>
+                {siblingIdx + 1}/{siblings.length}
+              </span>
+              <button
+                disabled={siblingIdx === siblings.length - 1}
+                onClick={() => onSwitchBranch(siblingIdx + 1)}
+              >
+                <ChevronRightIcon size={13} />
+              </button>
+            </>
+          ) : null}
           <button onClick={onCopy}>
             <CopyIcon size={13} />
           </button>
-          <button onClick={onDelete}>
-            <TrashIcon size={13} />
-          </button>
+          {block.role === "user" && onRegenerate ? (
+            <button onClick={() => onRegenerate(block.id)}>
+              <RefreshCwIcon size={13} />
+            </button>
+          ) : null}
+          {block.role === "user" && onEdit ? (
+            <button onClick={onEditClick}>
+              <PencilIcon size={13} />
+            </button>
+          ) : null}
+          {block.role === "assistant" && chatId !== undefined ? (
+            <button onClick={onDelete}>
+              <TrashIcon size={13} />
+            </button>
+          ) : null}
           <span>
             {new Date().toLocaleDateString()} {new Date().toLocaleTimeString()}
+            {block.model ? ` · ${block.model}` : null}
           </span>
         </div>
       ) : null}
@@ -61,6 +145,9 @@ const TextComponent: React.FC<TextComponentProps> = ({ block }) => {
 const MemoedTextComponent = React.memo(TextComponent, (prev, next) => {
   if (prev.block.id !== next.block.id) return false;
   if (prev.block.hash !== next.block.hash) return false;
+  if (prev.chatId !== next.chatId) return false;
+  if (!prev.onRegenerate !== !next.onRegenerate) return false;
+  if (!prev.onEdit !== !next.onEdit) return false;
   return true;
 });
 
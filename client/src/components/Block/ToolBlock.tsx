@@ -1,7 +1,7 @@
 import React, { useEffect, useState } from "react";
 import { ToolBlock } from "../../blocks";
-import { useBlock } from "../../hooks";
-import { tryOr } from "../../utils";
+import { useApp, useBlock } from "../../hooks";
+import { tryOr, useAtomWithSelector } from "../../utils";
 import styles from "./ToolBlock.module.css";
 
 function formatFunc(name: string, args: string): [string, string] {
@@ -15,7 +15,9 @@ type ToolComponentProps = {
 };
 const ToolComponent: React.FC<ToolComponentProps> = ({ block }) => {
   const { isActive } = useBlock();
+  const cancelToolCall = useAtomWithSelector(useApp().generation, (state) => state.cancelToolCall);
   const [name, args] = formatFunc(block.name, block.args);
+  const running = !block.result && !block.error && !!block.call_id && !!cancelToolCall;
   const [expanded, setExpanded] = useState(isActive);
   useEffect(() => {
     if (isActive) return;
@@ -24,6 +26,9 @@ const ToolComponent: React.FC<ToolComponentProps> = ({ block }) => {
   function onExpandOrCollapse() {
     setExpanded(!expanded);
   }
+  function onCancel() {
+    if (block.call_id) cancelToolCall?.(block.call_id);
+  }
   function onCopyArgs() {
     navigator.clipboard.writeText(args);
   }
@@ -44,6 +49,7 @@ const ToolComponent: React.FC<ToolComponentProps> = ({ block }) => {
       <div className={styles.header}>
         <span>{label}</span>
         <div className={styles.actions}>
+          {running ? <button onClick={onCancel}>cancel</button> : null}
           <button onClick={onExpandOrCollapse}>{expanded ? "collapse" : "expand"}</button>
           <button onClick={onCopyArgs}>copy args</button>
           <button onClick={onCopyOutput}>copy out</button>
@@ -15,6 +15,8 @@ const Divider: React.FC<DividerProps> = ({ prev, next }) => {
   const GAP_MD = 1;
   function getHeight() {
     if (!prev || !next) return 0;
+    if (next.type === "attachment") return GAP_SM;
+    if (prev.type === "attachment") return 1.5 * GAP_MD;
     if (
       (prev.type === "text" && prev.role === "user") ||
       (next.type === "text" && next.role === "user")
@@ -35,6 +37,9 @@ const Divider: React.FC<DividerProps> = ({ prev, next }) => {
     if (prev.type === "error" || next.type === "error") {
       return GAP_MD;
     }
+    if (prev.type === "notice" || next.type === "notice") {
+      return GAP_MD;
+    }
     return 0;
   }
   return <div style={{ height: `${getHeight()}em` }} />;
@@ -43,8 +48,10 @@ const Divider: React.FC<DividerProps> = ({ prev, next }) => {
 type ChatHistoryProps = {
   chatId: number;
   scrollRef: React.RefObject<HTMLDivElement>;
+  onRegenerate: (blockId: string) => void;
+  onEdit: (blockId: string, content: string) => void;
 };
-const ChatHistory: React.FC<ChatHistoryProps> = ({ chatId, scrollRef }) => {
+const ChatHistory: React.FC<ChatHistoryProps> = ({ chatId, scrollRef, onRegenerate, onEdit }) => {
   const streaming = useAtomWithSelector(useApp().generation, (state) => state.generating);
   let blocks = useAtomWithSelector(useApp().data, (data) => {
     const chat = data.chats.find((chat) => chat.id === chatId);
@@ -68,7 +75,14 @@ const ChatHistory: React.FC<ChatHistoryProps> = ({ chatId, scrollRef }) => {
                 child = <Block.Thinking block={b} />;
                 break;
               case "text":
-                child = <Block.Text block={b} />;
+                child = (
+                  <Block.Text
+                    block={b}
+                    chatId={chatId}
+                    onRegenerate={streaming ? undefined : onRegenerate}
+                    onEdit={streaming ? undefined : onEdit}
+                  />
+                );
                 break;
               case "tool":
                 child = <Block.Tool block={b} />;
@@ -76,6 +90,12 @@ const ChatHistory: React.FC<ChatHistoryProps> = ({ chatId, scrollRef }) => {
               case "error":
                 child = <Block.Error block={b} />;
                 break;
+              case "notice":
+                child = <Block.Notice block={b} />;
+                break;
+              case "attachment":
+                child = <Block.Attachment block={b} />;
+                break;
               default:
                 return [];
             }
@@ -1,14 +1,16 @@
 import { load, Store } from "@tauri-apps/plugin-store";
-import React, { useState } from "react";
+import React, { useRef, useState } from "react";
+import { UploadAttachmentResponse } from "../../api";
 import { useApp, useMount } from "../../hooks";
 import { useAtomWithSelector } from "../../utils";
 import styles from "./MessageBox.module.css";
 import { Actions } from "./components/Actions";
+import { Attachments } from "./components/Attachments";
 import { Textarea } from "./components/Textarea";
 
 type MessageBoxProps = {
   store: Store;
-  onSend: (message: string) => void;
+  onSend: (message: string, attachments: UploadAttachmentResponse[]) => void;
   onCancel: () => void;
 };
 const MessageBox: React.FC<MessageBoxProps> = ({ store, onSend, onCancel }) => {
@@ -20,6 +22,8 @@ const MessageBox: React.FC<MessageBoxProps> = ({ store, onSend, onCancel }) => {
     state.think,
   ]);
   const [message, setMessage] = useState("");
+  const [attachments, setAttachments] = useState<UploadAttachmentResponse[]>([]);
+  const fileInputRef = useRef<HTMLInputElement>(null);
   async function _onModelChange(newModelId: string, newPersonalityId: string) {
     const modelChanged = newModelId !== modelId;
     if (modelChanged) {
@@ -45,13 +49,36 @@ const MessageBox: React.FC<MessageBoxProps> = ({ store, onSend, onCancel }) => {
       think: think,
     }));
   }
+  async function _onFilesSelected(files: FileList | null) {
+    for (const file of Array.from(files ?? [])) {
+      try {
+        const attachment = await app.api.uploadAttachment(file, modelId);
+        setAttachments((attachments) => [...attachments, attachment]);
+      } catch (error) {
+        console.error(`error attaching "${file.name}":`, error);
+      }
+    }
+    if (fileInputRef.current) fileInputRef.current.value = "";
+  }
   async function _onSend() {
-    onSend(message);
+    onSend(message, attachments);
     setMessage("");
+    setAttachments([]);
   }
   return (
     <div className={styles.root}>
       <div className={styles.container}>
+        <input
+          ref={fileInputRef}
+          type="file"
+          multiple
+          hidden
+          onChange={(e) => _onFilesSelected(e.target.files)}
+        />
+        <Attachments
+          attachments={attachments}
+          onRemove={(id) => setAttachments((attachments) => attachments.filter((i) => i.id !== id))}
+        />
         <Textarea value={message} onChange={setMessage} onSend={_onSend} />
         <Actions
           modelId={modelId}
@@ -60,6 +87,7 @@ const MessageBox: React.FC<MessageBoxProps> = ({ store, onSend, onCancel }) => {
           think={think}
           onModelChange={_onModelChange}
           onConfigChange={_onConfigChange}
+          onAttach={() => fileInputRef.current?.click()}
           onSend={_onSend}
           onCancel={onCancel}
         />
@@ -1,4 +1,4 @@
-import { ArrowUp, ChevronDown, Lightbulb, Parentheses, Settings2 } from "lucide-react";
+import { ArrowUp, ChevronDown, Lightbulb, Paperclip, Parentheses, Settings2 } from "lucide-react";
 import React from "react";
 import { useApp } from "../../../hooks";
 import { useAtomWithSelector } from "../../../utils";
@@ -54,10 +54,12 @@ type GenerationConfigProps = {
   tools: boolean;
   think: boolean;
   onChange: (tools: boolean, think: boolean) => void;
+  onAttach: () => void;
 };
-const GenerationConfig: React.FC<GenerationConfigProps> = ({ tools, think, onChange }) => {
+const GenerationConfig: React.FC<GenerationConfigProps> = ({ tools, think, onChange, onAttach }) => {
   return (
     <div className={styles.generationConfig}>
+      <Button iconLeft={<Paperclip size={13} strokeWidth={2.5} />} onClick={onAttach} />
       <Button
         glowing={tools}
         label="tools"
@@ -105,6 +107,7 @@ type ActionsProps = {
   think: boolean;
   onModelChange: (modelId: string, personalityId: string) => void;
   onConfigChange: (tools: boolean, think: boolean) => void;
+  onAttach: () => void;
   onSend: () => void;
   onCancel: () => void;
 };
@@ -115,6 +118,7 @@ const Actions: React.FC<ActionsProps> = ({
   think,
   onModelChange,
   onConfigChange,
+  onAttach,
   onSend,
   onCancel,
 }) => {
@@ -122,7 +126,12 @@ const Actions: React.FC<ActionsProps> = ({
     <div className={styles.root}>
       <ModelPicker modelId={modelId} personalityId={personalityId} onChange={onModelChange} />
       <div className={styles.generationConfigAndSend}>
-        <GenerationConfig tools={tools} think={think} onChange={onConfigChange} />
+        <GenerationConfig
+          tools={tools}
+          think={think}
+          onChange={onConfigChange}
+          onAttach={onAttach}
+        />
         <SendButton onSend={onSend} onCancel={onCancel} />
       </div>
     </div>
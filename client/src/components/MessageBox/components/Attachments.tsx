@@ -0,0 +1,29 @@
+import { FileText, X } from "lucide-react";
+import React from "react";
+import { UploadAttachmentResponse } from "../../../api";
+import styles from "./Attachments.module.css";
+
+type AttachmentsProps = {
+  attachments: UploadAttachmentResponse[];
+  onRemove: (id: string) => void;
+};
+const Attachments: React.FC<AttachmentsProps> = ({ attachments, onRemove }) => {
+  if (!attachments.length) {
+    return null;
+  }
+  return (
+    <div className={styles.root}>
+      {attachments.map((attachment) => (
+        <div key={attachment.id} className={styles.attachment}>
+          <FileText size={13} strokeWidth={2.5} />
+          <span>{attachment.name}</span>
+          <button onClick={() => onRemove(attachment.id)}>
+            <X size={12} strokeWidth={2.5} />
+          </button>
+        </div>
+      ))}
+    </div>
+  );
+};
+
+export { Attachments };
@@ -13,6 +13,8 @@ type Settings = {
 
 type GenerationConfig = {
   generating: boolean;
+  cancelToolCall: ((callId: string) => void) | null;
+  stop: (() => void) | null;
   modelId: string;
   personalityId: string;
   tools: boolean;
@@ -54,6 +56,8 @@ const AppProvider: React.FC<AppProviderProps> = ({ children }) => {
     setGenerationAtom(
       atom({
         generating: false as boolean,
+        cancelToolCall: null,
+        stop: null,
         modelId: modelId ?? config.models[0].id,
         personalityId: personalityId ?? config.models[0].personalities[0].id,
         tools: tools ?? false,
@@ -0,0 +1,81 @@
+import { z } from "zod";
+import { AnyBlock } from "../blocks";
+
+const EventChat = z.object({
+  id: z.number(),
+  ts: z.string().datetime(),
+  updated_at: z.string().datetime(),
+  title: z.string(),
+  pinned: z.boolean(),
+  archived: z.boolean(),
+  forked_from: z.number().optional(),
+});
+const chatCreatedEvent = z.object({
+  jsonrpc: z.literal("2.0"),
+  method: z.literal("chat_created"),
+  params: EventChat,
+});
+const chatUpdatedEvent = z.object({
+  jsonrpc: z.literal("2.0"),
+  method: z.literal("chat_updated"),
+  params: EventChat,
+});
+const chatDeletedEvent = z.object({
+  jsonrpc: z.literal("2.0"),
+  method: z.literal("chat_deleted"),
+  params: z.object({ id: z.number() }),
+});
+const blockEvent = z.object({
+  jsonrpc: z.literal("2.0"),
+  method: z.literal("block"),
+  params: z.object({ chat_id: z.number(), block: AnyBlock }),
+});
+const ChatEvent = z.union([chatCreatedEvent, chatUpdatedEvent, chatDeletedEvent, blockEvent]);
+type ChatEvent = z.infer<typeof ChatEvent>;
+
+const RECONNECT_DELAY_MS = 2_000;
+
+// subscribeEvents keeps a subscription to the server's chat events open until the returned function is
+// called. As events may have been missed while disconnected, `onResync` is called on every reconnect.
+function subscribeEvents(
+  baseUrl: string,
+  apiKey: string,
+  onEvent: (event: ChatEvent) => void,
+  onResync: () => void
+): () => void {
+  const wsBaseUrl = baseUrl.replace(/^http/, "ws");
+  const wsUrl = `${wsBaseUrl}/events?api_key=${encodeURIComponent(apiKey)}`;
+  let socket: WebSocket | null = null;
+  let closed = false;
+  let timeout: ReturnType<typeof setTimeout> | null = null;
+  function connect(reconnect: boolean) {
+    socket = new WebSocket(wsUrl);
+    socket.onopen = () => {
+      if (reconnect) onResync();
+    };
+    socket.onmessage = (event) => {
+      try {
+        const parsed = ChatEvent.safeParse(JSON.parse(event.data));
+        if (!parsed.success) {
+          console.error(`received an unexpected event: ${event.data}`);
+          return;
+        }
+        onEvent(parsed.data);
+      } catch (error) {
+        console.error("error parsing event:", error);
+      }
+    };
+    socket.onclose = () => {
+      if (closed) return;
+      timeout = setTimeout(() => connect(true), RECONNECT_DELAY_MS);
+    };
+  }
+  connect(false);
+  return () => {
+    closed = true;
+    if (timeout) clearTimeout(timeout);
+    socket?.close();
+  };
+}
+
+export { ChatEvent, subscribeEvents };
@@ -1,4 +1,5 @@
 export * from "./atom";
+export * from "./events";
 export * from "./llm";
 export * from "./never";
 export * from "./resolvable";
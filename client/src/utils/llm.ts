@@ -11,14 +11,59 @@ const toolCallMessage = z.object({
     args: z.string(),
   }),
 });
+const toolApprovalMessage = z.object({
+  jsonrpc: z.literal("2.0"),
+  id: z.number(),
+  method: z.literal("tool_approval"),
+  params: z.object({
+    name: z.string(),
+    args: z.string(),
+  }),
+});
 const blockNotification = z.object({
   jsonrpc: z.literal("2.0"),
   method: z.literal("block"),
   params: AnyBlock,
 });
-const StreamMessage = z.union([toolCallMessage, blockNotification]);
+const truncateNotification = z.object({
+  jsonrpc: z.literal("2.0"),
+  method: z.literal("truncate"),
+  params: z.object({ block_id: z.string() }),
+});
+const StreamMessage = z.union([
+  toolCallMessage,
+  toolApprovalMessage,
+  blockNotification,
+  truncateNotification,
+]);
 type StreamMessage = z.infer<typeof StreamMessage>;
 
+type CompletionRequest =
+  | {
+      method: "generate";
+      content: string;
+      attachments?: { id: string; name: string; media_type: string }[];
+    }
+  | { method: "regenerate"; eventId: string }
+  | { method: "edit"; eventId: string; content: string }
+  | { method: "attach" };
+
+function completionRequestParams(request: CompletionRequest) {
+  switch (request.method) {
+    case "generate":
+      return {
+        content: request.content,
+        attachments: request.attachments?.map((i) => i.id) ?? [],
+      };
+    case "regenerate":
+      return { event_id: request.eventId };
+    case "edit":
+      return { event_id: request.eventId, content: request.content };
+    case "attach":
+      return {};
+  }
+}
+
 async function streamCompletion(
   baseUrl: string,
   apiKey: string,
@@ -27,9 +72,10 @@ async function streamCompletion(
   personalityId: string,
   useTools: boolean,
   think: boolean,
-  content: string,
+  request: CompletionRequest,
   tools: Tool[],
-  onMessage: (message: StreamMessage) => void
+  onMessage: (message: StreamMessage) => void,
+  onNotify?: (notify: (method: string, params: Record<string, unknown>) => void) => void
 ): Promise<void> {
   const wsBaseUrl = baseUrl.replace(/^http/, "ws");
   const wsUrl = `${wsBaseUrl}/chats/${chatId}?api_key=${encodeURIComponent(apiKey)}`;
@@ -39,17 +85,21 @@ async function streamCompletion(
       socket.send(
         JSON.stringify({
           jsonrpc: "2.0",
-          method: "generate",
+          method: request.method,
           params: {
+            ...completionRequestParams(request),
             model_id: modelId,
             personality_id: personalityId,
-            content: content,
             tools: tools.map((tool) => ({ name: tool.Name, spec: tool.Spec })),
             use_tools: useTools,
             think: think,
           },
         })
       );
+      onNotify?.((method, params) => {
+        if (socket.readyState !== WebSocket.OPEN) return;
+        socket.send(JSON.stringify({ jsonrpc: "2.0", method: method, params: params }));
+      });
     };
     socket.onmessage = (event) => {
       try {
@@ -59,6 +109,19 @@ async function streamCompletion(
           return;
         }
         const data = parsed.data;
+        if (data.method === "tool_approval") {
+          const approved = window.confirm(
+            `Allow the assistant to call "${data.params.name}" with the following arguments?\n\n${data.params.args}`
+          );
+          socket.send(
+            JSON.stringify({
+              jsonrpc: "2.0",
+              result: { approved: approved },
+              id: data.id,
+            })
+          );
+          return;
+        }
         if (data.method === "tool_call") {
           const tool = tools.find((tool) => tool.Name === data.params.name);
           if (tool) {
@@ -124,3 +187,4 @@ async function streamCompletion(
 }
 
 export { streamCompletion, StreamMessage };
+export type { CompletionRequest };
@@ -28,7 +28,9 @@ func main() {
 			juttele.NewAnthropicModel(anthropicToken, "claude-3-7-sonnet-20250219",
 				juttele.WithDisplayName("Claude 3.7 Sonnet"),
 				juttele.WithMaxTokens(16384),
+				juttele.WithModalities(juttele.ModalityImage, juttele.ModalityDocument),
 				juttele.WithPersonality("Raw", rawSystemPrompt),
+				juttele.WithPromptCaching(),
 				juttele.WithTemperature(0.7),
 			),
 		),
@@ -36,7 +38,9 @@ func main() {
 			juttele.NewAnthropicModel(anthropicToken, "claude-3-5-sonnet-20241022",
 				juttele.WithDisplayName("Claude 3.5 Sonnet"),
 				juttele.WithMaxTokens(8192),
+				juttele.WithModalities(juttele.ModalityImage, juttele.ModalityDocument),
 				juttele.WithPersonality("Raw", rawSystemPrompt),
+				juttele.WithPromptCaching(),
 				juttele.WithTemperature(0.7),
 			),
 		),
@@ -60,6 +64,7 @@ func main() {
 			juttele.NewOpenRouterModel(openRouterToken, "google/gemini-2.5-pro", []string{"Google AI Studio"},
 				juttele.WithDisplayName("Gemini 2.5 Pro"),
 				juttele.WithMaxTokens(16384),
+				juttele.WithModalities(juttele.ModalityImage, juttele.ModalityDocument),
 				juttele.WithPersonality("Raw", rawSystemPrompt),
 				juttele.WithTemperature(1.0),
 			),
@@ -68,6 +73,7 @@ func main() {
 			juttele.NewOpenRouterModel(openRouterToken, "google/gemini-2.5-flash", []string{"Google AI Studio"},
 				juttele.WithDisplayName("Gemini 2.5 Flash"),
 				juttele.WithMaxTokens(16384),
+				juttele.WithModalities(juttele.ModalityImage, juttele.ModalityDocument),
 				juttele.WithPersonality("Raw", rawSystemPrompt),
 				juttele.WithTemperature(1.0),
 			),
@@ -76,6 +82,7 @@ func main() {
 			juttele.NewOpenRouterModel(openRouterToken, "openai/gpt-4o-2024-11-20", nil,
 				juttele.WithDisplayName("GPT-4o"),
 				juttele.WithMaxTokens(8192),
+				juttele.WithModalities(juttele.ModalityImage),
 				juttele.WithPersonality("Raw", rawSystemPrompt),
 				juttele.WithTemperature(1.0),
 			),
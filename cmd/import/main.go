@@ -0,0 +1,60 @@
+// Command import imports chats from the data exports of other chat services into juttele.
+//
+//	go run ./cmd/import -format chatgpt -data ./.data conversations.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/markusylisiurunen/juttele"
+)
+
+func main() {
+	var (
+		format     = flag.String("format", "", `the export format, either "chatgpt" or "claude"`)
+		dataFolder = flag.String("data", "./.data", "the juttele data folder")
+	)
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: import -format chatgpt|claude [-data folder] conversations.json")
+		os.Exit(2)
+	}
+	if err := run(context.Background(), *format, *dataFolder, flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, format, dataFolder, file string) error {
+	if info, err := os.Stat(dataFolder); err != nil || !info.IsDir() {
+		return fmt.Errorf("data folder %q does not exist", dataFolder)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var chats []juttele.ImportedChat
+	switch format {
+	case "chatgpt":
+		chats, err = juttele.ParseChatGPTExport(f)
+	case "claude":
+		chats, err = juttele.ParseClaudeExport(f)
+	default:
+		return fmt.Errorf("unknown format: %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing export: %w", err)
+	}
+	app := juttele.New("", juttele.WithDataFolder(dataFolder))
+	defer app.Close()
+	ids, err := app.ImportChats(ctx, chats)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d of %d chats\n", len(ids), len(chats))
+	return nil
+}
@@ -0,0 +1,180 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+	"github.com/markusylisiurunen/juttele/internal/util/jsonrpc"
+	"github.com/tidwall/gjson"
+)
+
+var errNoClientAttached = errors.New("no client is attached to the generation")
+
+// generationJob is a generation running detached from the connection which started it. Clients attach
+// to it to receive its blocks and to serve its client tools, and may disconnect and re-attach at will.
+type generationJob struct {
+	chatID    int64
+	turnUUID  string
+	stop      context.CancelCauseFunc
+	toolCalls *ToolCallRegistry
+	done      chan struct{}
+
+	mu    sync.Mutex
+	proxy *webSocketProxy
+}
+
+func newGenerationJob(
+	chatID int64, turnUUID string, proxy *webSocketProxy, stop context.CancelCauseFunc,
+) *generationJob {
+	return &generationJob{
+		chatID:   chatID,
+		turnUUID: turnUUID,
+		stop:     stop,
+		done:     make(chan struct{}),
+		proxy:    proxy,
+	}
+}
+
+// publish sends a block to the attached client, if any. A client which can't be written to is
+// detached, as it will replay the block when it re-attaches.
+func (job *generationJob) publish(block Block) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.proxy == nil {
+		return
+	}
+	if err := job.proxy.write(jsonrpc.NewNotification("block", block)); err != nil {
+		logger.Get().Error(fmt.Sprintf("error writing block message: %v", err))
+		job.proxy = nil
+	}
+}
+
+// serve handles the client's notifications for the job until either the generation is done or the
+// client disconnects.
+func (job *generationJob) serve(proxy *webSocketProxy) {
+	proxy.handle("stop", func(params json.RawMessage) {
+		job.stop(errGenerationStopped)
+	})
+	proxy.handle("cancel_tool_call", func(params json.RawMessage) {
+		callID := gjson.GetBytes(params, "call_id").String()
+		if job.toolCalls == nil || !job.toolCalls.Cancel(callID) {
+			logger.Get().Error(fmt.Sprintf("no in-flight tool call with ID %q", callID))
+		}
+	})
+	select {
+	case <-job.done:
+	case <-proxy.closeChan:
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.proxy == proxy {
+		job.proxy = nil
+	}
+}
+
+// rpc sends a request to the attached client, so that client tools and tool approvals follow the client
+// across reconnects.
+func (job *generationJob) rpc(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	job.mu.Lock()
+	proxy := job.proxy
+	job.mu.Unlock()
+	if proxy == nil {
+		return nil, errNoClientAttached
+	}
+	return proxy.rpc(ctx, method, params)
+}
+
+// ---
+
+// registerGenerationJob registers the job as the chat's running generation. It reports false if the chat
+// already has one.
+func (app *App) registerGenerationJob(job *generationJob) bool {
+	app.jobsMu.Lock()
+	defer app.jobsMu.Unlock()
+	if _, ok := app.jobs[job.chatID]; ok {
+		return false
+	}
+	app.jobs[job.chatID] = job
+	return true
+}
+
+func (app *App) unregisterGenerationJob(job *generationJob) {
+	app.jobsMu.Lock()
+	defer app.jobsMu.Unlock()
+	if app.jobs[job.chatID] == job {
+		delete(app.jobs, job.chatID)
+	}
+}
+
+// stopGenerationJob stops the chat's running generation, if any, and waits for it to finish so that
+// it no longer writes to the chat.
+func (app *App) stopGenerationJob(ctx context.Context, chatID int64) error {
+	app.jobsMu.Lock()
+	job := app.jobs[chatID]
+	delete(app.jobs, chatID)
+	app.jobsMu.Unlock()
+	if job == nil {
+		return nil
+	}
+	job.stop(errGenerationStopped)
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (app *App) getGenerationJob(chatID int64) *generationJob {
+	app.jobsMu.Lock()
+	defer app.jobsMu.Unlock()
+	return app.jobs[chatID]
+}
+
+// runGenerationJob publishes the blocks to the job's clients until the generation is done.
+func (app *App) runGenerationJob(job *generationJob, blocks <-chan Block) {
+	go func() {
+		defer close(job.done)
+		defer job.stop(nil)
+		defer app.unregisterGenerationJob(job)
+		for block := range blocks {
+			job.publish(block)
+		}
+	}()
+}
+
+// attachGenerationJob replays the blocks of the job's turn to a re-attaching client and then attaches it
+// to the job for the live blocks.
+func (app *App) attachGenerationJob(ctx context.Context, job *generationJob, proxy *webSocketProxy) error {
+	// NOTE: blocks are persisted before they are published, so holding the lock while replaying
+	// guarantees that the client misses none of them
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+		ChatID:      job.chatID,
+		AllBranches: true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, i := range events.Items {
+		if i.ParentUUID != job.turnUUID || !strings.HasPrefix(i.Kind, "block.") {
+			continue
+		}
+		block, err := parseBlock(i.Content)
+		if err != nil {
+			return err
+		}
+		if err := proxy.write(jsonrpc.NewNotification("block", block)); err != nil {
+			return err
+		}
+	}
+	job.proxy = proxy
+	return nil
+}
@@ -0,0 +1,43 @@
+package juttele
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStopGenerationJob(t *testing.T) {
+	app := New("token")
+	if err := app.stopGenerationJob(context.Background(), 1); err != nil {
+		t.Fatalf("stopping without a job: %v", err)
+	}
+	ctx, stop := context.WithCancelCause(context.Background())
+	job := newGenerationJob(1, "turn", nil, stop)
+	if !app.registerGenerationJob(job) {
+		t.Fatal("job not registered")
+	}
+	blocks := make(chan Block)
+	go func() {
+		defer close(blocks)
+		<-ctx.Done()
+		// NOTE: the generation keeps writing for a moment after being stopped
+		time.Sleep(10 * time.Millisecond)
+		blocks <- NewNoticeBlock("stopped", "The generation was stopped.")
+	}()
+	app.runGenerationJob(job, blocks)
+	if err := app.stopGenerationJob(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-job.done:
+	default:
+		t.Error("returned before the generation finished")
+	}
+	if !errors.Is(context.Cause(ctx), errGenerationStopped) {
+		t.Errorf("cause = %v, want %v", context.Cause(ctx), errGenerationStopped)
+	}
+	if app.getGenerationJob(1) != nil {
+		t.Error("job is still registered")
+	}
+}
@@ -0,0 +1,96 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+	"github.com/markusylisiurunen/juttele/internal/util/jsonrpc"
+)
+
+// hubBufferSize is the number of events a subscriber may fall behind before it is dropped.
+const hubBufferSize = 256
+
+// eventHub broadcasts changes to the chats to every subscribed client.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan jsonrpc.Notification]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan jsonrpc.Notification]struct{})}
+}
+
+// subscribe returns a channel of the published events and a function to unsubscribe with. The channel is
+// closed if the subscriber falls too far behind, after which it should resync and subscribe again.
+func (h *eventHub) subscribe() (<-chan jsonrpc.Notification, func()) {
+	ch := make(chan jsonrpc.Notification, hubBufferSize)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *eventHub) publish(method string, params any) {
+	msg := jsonrpc.NewNotification(method, params)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- msg:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// ---
+
+// publishChat broadcasts the current state of the chat with the given method, either "chat_created" or
+// "chat_updated".
+func (app *App) publishChat(ctx context.Context, method string, chatID int64) {
+	chat, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: chatID})
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error getting chat: %v", err))
+		return
+	}
+	v := chatsResponse_Chat{
+		ID:        chat.ID,
+		Ts:        chat.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: chat.UpdatedAt.Format(time.RFC3339Nano),
+		Title:     chat.Title,
+		Pinned:    chat.Pinned,
+		Archived:  chat.Archived,
+	}
+	if chat.ForkedFromChatID != 0 {
+		v.ForkedFrom = &chat.ForkedFromChatID
+	}
+	app.hub.publish(method, v)
+}
+
+func (app *App) publishChatDeleted(chatID int64) {
+	type params struct {
+		ID int64 `json:"id"`
+	}
+	app.hub.publish("chat_deleted", params{ID: chatID})
+}
+
+func (app *App) publishBlock(chatID int64, block json.RawMessage) {
+	type params struct {
+		ChatID int64           `json:"chat_id"`
+		Block  json.RawMessage `json:"block"`
+	}
+	app.hub.publish("block", params{ChatID: chatID, Block: block})
+}
@@ -0,0 +1,145 @@
+package juttele
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+	"github.com/markusylisiurunen/juttele/internal/util"
+)
+
+// importNamespace scopes the IDs of imported messages so that importing the same export twice is
+// detected.
+var importNamespace = uuid.MustParse("5f0a3d4e-8c1b-4f57-9a7e-2b6d0c9e1f38")
+
+// ImportedChat is a chat parsed from the data export of another chat service.
+type ImportedChat struct {
+	// Source names the service the chat was exported from, such as "chatgpt" or "claude".
+	Source    string
+	SourceID  string
+	Title     string
+	CreatedAt time.Time
+	Messages  []ImportedMessage
+}
+
+type ImportedMessage struct {
+	SourceID  string
+	Role      MessageType
+	Content   string
+	Model     string
+	CreatedAt time.Time
+}
+
+// ImportChats stores the chats as native ones, skipping those imported before. It returns the IDs of
+// the created chats. The database is opened if the app is not serving already.
+func (app *App) ImportChats(ctx context.Context, chats []ImportedChat) ([]int64, error) {
+	if app.repo == nil {
+		if err := app.initDatabase(ctx); err != nil {
+			return nil, err
+		}
+	}
+	ids := make([]int64, 0, len(chats))
+	for _, chat := range chats {
+		args := importedChatEvents(chat)
+		if len(args.Events) == 0 {
+			continue
+		}
+		exists, err := app.repo.HasChatEvent(ctx, repo.HasChatEventArgs{UUID: args.Events[0].UUID})
+		if err != nil {
+			return ids, err
+		}
+		if exists {
+			continue
+		}
+		id, err := app.repo.CreateChatWithEvents(ctx, args)
+		if err != nil {
+			return ids, fmt.Errorf("error importing chat %q: %w", chat.SourceID, err)
+		}
+		ids = append(ids, id)
+		app.publishChat(ctx, "chat_created", id)
+	}
+	return ids, nil
+}
+
+// importedChatEvents maps the chat onto user and assistant messages with their text blocks. Every
+// assistant message belongs to the turn of the user message before it, so assistant messages before
+// the first user message are dropped.
+func importedChatEvents(chat ImportedChat) repo.CreateChatWithEventsArgs {
+	args := repo.CreateChatWithEventsArgs{
+		CreatedAt: chat.CreatedAt,
+		Title:     chat.Title,
+	}
+	if args.Title == "" {
+		args.Title = "Imported chat"
+	}
+	turnUUID := ""
+	for idx, m := range chat.Messages {
+		if strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		sourceID := m.SourceID
+		if sourceID == "" {
+			sourceID = fmt.Sprintf("%s#%d", chat.SourceID, idx)
+		}
+		id := func(kind string) string {
+			return uuid.NewSHA1(importNamespace, []byte(chat.Source+":"+sourceID+":"+kind)).String()
+		}
+		createdAt := m.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = chat.CreatedAt
+		}
+		var (
+			message Message
+			block   *TextBlock
+		)
+		switch m.Role {
+		case MessageTypeUser:
+			msg := NewUserMessage(m.Content)
+			msg.ID = id("message")
+			message = msg
+			block = NewTextBlock("user", m.Content)
+		case MessageTypeAssistant:
+			if turnUUID == "" {
+				continue
+			}
+			msg := NewAssistantMessage(m.Content)
+			msg.ID = id("message")
+			if m.Model != "" {
+				msg.SetPersistedMeta("model_name", m.Model)
+			}
+			message = msg
+			block = NewTextBlock("assistant", m.Content)
+			block.Model = m.Model
+		default:
+			continue
+		}
+		message.SetPersistedMeta("imported_from", chat.Source)
+		block.ID = id("block")
+		block.Timestamp = createdAt.UTC()
+		parentUUID := turnUUID
+		if m.Role == MessageTypeUser {
+			turnUUID = message.GetID()
+			args.ActiveEventUUID = turnUUID
+		}
+		args.Events = append(args.Events,
+			repo.ChatEvent{
+				CreatedAt:  createdAt,
+				ParentUUID: parentUUID,
+				UUID:       message.GetID(),
+				Kind:       fmt.Sprintf("message.%s", message.GetType()),
+				Content:    util.Must(message.MarshalJSON()),
+			},
+			repo.ChatEvent{
+				CreatedAt:  createdAt,
+				ParentUUID: turnUUID,
+				UUID:       block.GetID(),
+				Kind:       fmt.Sprintf("block.%s", block.GetType()),
+				Content:    util.Must(block.MarshalJSON()),
+			},
+		)
+	}
+	return args
+}
@@ -0,0 +1,139 @@
+package juttele
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	chatGPTExport_Message struct {
+		ID     string `json:"id"`
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		CreateTime *float64 `json:"create_time"`
+		Content    struct {
+			ContentType string            `json:"content_type"`
+			Parts       []json.RawMessage `json:"parts"`
+		} `json:"content"`
+		Recipient string `json:"recipient"`
+		Metadata  struct {
+			ModelSlug        string `json:"model_slug"`
+			IsVisuallyHidden bool   `json:"is_visually_hidden_from_conversation"`
+		} `json:"metadata"`
+	}
+	chatGPTExport_Node struct {
+		ID      string                 `json:"id"`
+		Message *chatGPTExport_Message `json:"message"`
+		Parent  string                 `json:"parent"`
+	}
+	chatGPTExport_Conversation struct {
+		ID          string                        `json:"id"`
+		Title       string                        `json:"title"`
+		CreateTime  float64                       `json:"create_time"`
+		Mapping     map[string]chatGPTExport_Node `json:"mapping"`
+		CurrentNode string                        `json:"current_node"`
+	}
+)
+
+// ParseChatGPTExport parses the conversations.json file of a ChatGPT data export. Only the branch of
+// each conversation that was last shown is imported, and only its text content.
+func ParseChatGPTExport(r io.Reader) ([]ImportedChat, error) {
+	chats := make([]ImportedChat, 0)
+	err := decodeJSONArray(r, func(c chatGPTExport_Conversation) error {
+		chat := ImportedChat{
+			Source:    "chatgpt",
+			SourceID:  c.ID,
+			Title:     c.Title,
+			CreatedAt: chatGPTTime(c.CreateTime),
+			Messages:  make([]ImportedMessage, 0),
+		}
+		// NOTE: the conversation is a tree, so walk from the current node up to the root
+		path := []chatGPTExport_Node{}
+		seen := map[string]bool{}
+		for id := c.CurrentNode; id != "" && !seen[id]; {
+			seen[id] = true
+			node, ok := c.Mapping[id]
+			if !ok {
+				break
+			}
+			path = append(path, node)
+			id = node.Parent
+		}
+		slices.Reverse(path)
+		for _, node := range path {
+			m := node.Message
+			if m == nil || m.Metadata.IsVisuallyHidden || (m.Recipient != "" && m.Recipient != "all") {
+				continue
+			}
+			if m.Content.ContentType != "text" && m.Content.ContentType != "multimodal_text" {
+				continue
+			}
+			var role MessageType
+			switch m.Author.Role {
+			case "user":
+				role = MessageTypeUser
+			case "assistant":
+				role = MessageTypeAssistant
+			default:
+				continue
+			}
+			parts := make([]string, 0, len(m.Content.Parts))
+			for _, p := range m.Content.Parts {
+				var s string
+				if err := json.Unmarshal(p, &s); err != nil {
+					continue // NOTE: images and other attachments are not imported
+				}
+				parts = append(parts, s)
+			}
+			var createdAt time.Time
+			if m.CreateTime != nil {
+				createdAt = chatGPTTime(*m.CreateTime)
+			}
+			chat.Messages = append(chat.Messages, ImportedMessage{
+				SourceID:  m.ID,
+				Role:      role,
+				Content:   strings.Join(parts, "\n\n"),
+				Model:     m.Metadata.ModelSlug,
+				CreatedAt: createdAt,
+			})
+		}
+		chats = append(chats, chat)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+func chatGPTTime(v float64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// decodeJSONArray decodes the elements of a JSON array one at a time.
+func decodeJSONArray[T any](r io.Reader, fn func(T) error) error {
+	decoder := json.NewDecoder(r)
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		var v T
+		if err := decoder.Decode(&v); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
@@ -0,0 +1,125 @@
+package juttele
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChatGPTExport(t *testing.T) {
+	// NOTE: "a1" and "a2" are two answers to the same question, and "u2" continues the second one
+	mapping := `{
+		"root": {"id": "root", "message": null, "parent": ""},
+		"sys": {"id": "sys", "parent": "root", "message": {"id": "sys", "author": {"role": "system"},
+			"content": {"content_type": "text", "parts": [""]},
+			"metadata": {"is_visually_hidden_from_conversation": true}}},
+		"u1": {"id": "u1", "parent": "sys", "message": {"id": "u1", "author": {"role": "user"}, "create_time": 1700000000.5,
+			"content": {"content_type": "multimodal_text", "parts": [{"asset_pointer": "file-1"}, "What is this?"]}}},
+		"a1": {"id": "a1", "parent": "u1", "message": {"id": "a1", "author": {"role": "assistant"}, "recipient": "all",
+			"content": {"content_type": "text", "parts": ["A cat."]}, "metadata": {"model_slug": "gpt-4o"}}},
+		"a2": {"id": "a2", "parent": "u1", "message": {"id": "a2", "author": {"role": "assistant"}, "recipient": "all",
+			"content": {"content_type": "text", "parts": ["A dog.", "Probably."]}, "metadata": {"model_slug": "gpt-4o"}}},
+		"call": {"id": "call", "parent": "a2", "message": {"id": "call", "author": {"role": "assistant"}, "recipient": "browser",
+			"content": {"content_type": "code", "parts": ["search('dog')"]}}},
+		"tool": {"id": "tool", "parent": "call", "message": {"id": "tool", "author": {"role": "tool"},
+			"content": {"content_type": "text", "parts": ["results"]}}},
+		"u2": {"id": "u2", "parent": "tool", "message": {"id": "u2", "author": {"role": "user"},
+			"content": {"content_type": "text", "parts": ["Thanks!"]}}}
+	}`
+	tests := []struct {
+		name        string
+		currentNode string
+		mapping     string
+		want        []string
+	}{
+		{
+			name:        "current branch",
+			currentNode: "u2",
+			mapping:     mapping,
+			want:        []string{"user: What is this?", "assistant: A dog.\n\nProbably.", "user: Thanks!"},
+		},
+		{
+			name:        "other branch",
+			currentNode: "a1",
+			mapping:     mapping,
+			want:        []string{"user: What is this?", "assistant: A cat."},
+		},
+		{
+			name:        "missing current node",
+			currentNode: "missing",
+			mapping:     mapping,
+			want:        []string{},
+		},
+		{
+			name:        "missing parent",
+			currentNode: "a1",
+			mapping: `{"a1": {"id": "a1", "parent": "gone", "message": {"id": "a1", "author": {"role": "assistant"},
+				"content": {"content_type": "text", "parts": ["Orphan."]}}}}`,
+			want: []string{"assistant: Orphan."},
+		},
+		{
+			name:        "cycle",
+			currentNode: "x",
+			mapping: `{
+				"x": {"id": "x", "parent": "y", "message": {"id": "x", "author": {"role": "user"},
+					"content": {"content_type": "text", "parts": ["X"]}}},
+				"y": {"id": "y", "parent": "x", "message": {"id": "y", "author": {"role": "assistant"},
+					"content": {"content_type": "text", "parts": ["Y"]}}}
+			}`,
+			want: []string{"assistant: Y", "user: X"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := `[{"id": "c1", "title": "Pets", "create_time": 1700000000, "current_node": "` +
+				tt.currentNode + `", "mapping": ` + tt.mapping + `}]`
+			chats, err := ParseChatGPTExport(strings.NewReader(export))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(chats) != 1 {
+				t.Fatalf("got %d chats, want 1", len(chats))
+			}
+			chat := chats[0]
+			if chat.Source != "chatgpt" || chat.SourceID != "c1" || chat.Title != "Pets" ||
+				!chat.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("chat = %+v", chat)
+			}
+			got := make([]string, 0, len(chat.Messages))
+			for _, m := range chat.Messages {
+				got = append(got, string(m.Role)+": "+m.Content)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChatGPTExportMessageFields(t *testing.T) {
+	export := `[{"id": "c1", "title": "T", "current_node": "a1", "mapping": {
+		"u1": {"id": "u1", "parent": "", "message": {"id": "u1", "author": {"role": "user"}, "create_time": 1700000000.5,
+			"content": {"content_type": "text", "parts": ["Hi"]}}},
+		"a1": {"id": "a1", "parent": "u1", "message": {"id": "a1", "author": {"role": "assistant"},
+			"content": {"content_type": "text", "parts": ["Hello"]}, "metadata": {"model_slug": "gpt-4o"}}}
+	}}, {"id": "c2", "title": "Empty", "mapping": {}}]`
+	chats, err := ParseChatGPTExport(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chats) != 2 || len(chats[1].Messages) != 0 {
+		t.Fatalf("chats = %+v", chats)
+	}
+	user, assistant := chats[0].Messages[0], chats[0].Messages[1]
+	if user.SourceID != "u1" || !user.CreatedAt.Equal(time.Unix(1700000000, 5e8)) || user.Model != "" {
+		t.Errorf("user message = %+v", user)
+	}
+	if assistant.SourceID != "a1" || !assistant.CreatedAt.IsZero() || assistant.Model != "gpt-4o" {
+		t.Errorf("assistant message = %+v", assistant)
+	}
+
+	if _, err := ParseChatGPTExport(strings.NewReader(`{"id": "c1"}`)); err == nil {
+		t.Errorf("expected an error for an export which is not an array")
+	}
+}
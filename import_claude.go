@@ -0,0 +1,82 @@
+package juttele
+
+import (
+	"cmp"
+	"io"
+	"strings"
+	"time"
+)
+
+type (
+	claudeExport_Message struct {
+		UUID      string    `json:"uuid"`
+		Text      string    `json:"text"`
+		Sender    string    `json:"sender"`
+		Model     string    `json:"model"`
+		CreatedAt time.Time `json:"created_at"`
+		Content   []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	claudeExport_Conversation struct {
+		UUID         string                 `json:"uuid"`
+		Name         string                 `json:"name"`
+		Model        string                 `json:"model"`
+		CreatedAt    time.Time              `json:"created_at"`
+		ChatMessages []claudeExport_Message `json:"chat_messages"`
+	}
+)
+
+// ParseClaudeExport parses the conversations.json file of a Claude data export. Only the text
+// content of the messages is imported, along with the model of the answers where the export has it.
+func ParseClaudeExport(r io.Reader) ([]ImportedChat, error) {
+	chats := make([]ImportedChat, 0)
+	err := decodeJSONArray(r, func(c claudeExport_Conversation) error {
+		chat := ImportedChat{
+			Source:    "claude",
+			SourceID:  c.UUID,
+			Title:     c.Name,
+			CreatedAt: c.CreatedAt,
+			Messages:  make([]ImportedMessage, 0, len(c.ChatMessages)),
+		}
+		for _, m := range c.ChatMessages {
+			var (
+				role  MessageType
+				model string
+			)
+			switch m.Sender {
+			case "human":
+				role = MessageTypeUser
+			case "assistant":
+				role = MessageTypeAssistant
+				model = cmp.Or(m.Model, c.Model)
+			default:
+				continue
+			}
+			content := m.Text
+			if len(m.Content) > 0 {
+				parts := make([]string, 0, len(m.Content))
+				for _, i := range m.Content {
+					if i.Type == "text" && i.Text != "" {
+						parts = append(parts, i.Text)
+					}
+				}
+				content = strings.Join(parts, "\n\n")
+			}
+			chat.Messages = append(chat.Messages, ImportedMessage{
+				SourceID:  m.UUID,
+				Role:      role,
+				Content:   content,
+				Model:     model,
+				CreatedAt: m.CreatedAt,
+			})
+		}
+		chats = append(chats, chat)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
@@ -0,0 +1,43 @@
+package juttele
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseClaudeExport(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		want  []string
+	}{
+		{name: "without a model", want: []string{"", "", "", "claude-3-opus"}},
+		{name: "with a conversation model", model: `"model": "claude-3-5-sonnet",`, want: []string{"", "claude-3-5-sonnet", "", "claude-3-opus"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := `[{"uuid": "c1", "name": "Pets", ` + tt.model + ` "created_at": "2024-01-01T12:00:00Z", "chat_messages": [
+				{"uuid": "m1", "sender": "human", "text": "What is this?", "created_at": "2024-01-01T12:00:00Z"},
+				{"uuid": "m2", "sender": "assistant", "created_at": "2024-01-01T12:00:01Z",
+					"content": [{"type": "text", "text": "A cat."}, {"type": "tool_use"}, {"type": "text", "text": "Probably."}]},
+				{"uuid": "m3", "sender": "human", "text": "Thanks!", "created_at": "2024-01-01T12:00:02Z"},
+				{"uuid": "m4", "sender": "assistant", "model": "claude-3-opus", "text": "You're welcome.", "created_at": "2024-01-01T12:00:03Z"}
+			]}]`
+			chats, err := ParseClaudeExport(strings.NewReader(export))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(chats) != 1 || len(chats[0].Messages) != 4 {
+				t.Fatalf("chats = %+v", chats)
+			}
+			if got := chats[0].Messages[1].Content; got != "A cat.\n\nProbably." {
+				t.Errorf("content = %q", got)
+			}
+			for idx, m := range chats[0].Messages {
+				if m.Model != tt.want[idx] {
+					t.Errorf("message %d: model = %q, want %q", idx, m.Model, tt.want[idx])
+				}
+			}
+		})
+	}
+}
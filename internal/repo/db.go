@@ -2,10 +2,16 @@ package repo
 
 import "database/sql"
 
+// timeFormat is the fixed-width format of the stored timestamps, which keeps their text order the
+// same as their chronological one. time.RFC3339Nano would drop trailing zeros.
+const timeFormat = "2006-01-02T15:04:05.000000000Z"
+
 type Repository struct {
 	db *sql.DB
+	// search is set by SetupSearch when the full-text search index is available.
+	search bool
 }
 
 func New(db *sql.DB) *Repository {
-	return &Repository{db}
+	return &Repository{db: db}
 }
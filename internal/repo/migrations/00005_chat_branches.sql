@@ -0,0 +1,33 @@
+-- record the parent of each chat event
+alter table chat_events add column chat_event_parent_uuid text;
+
+-- user messages point to the previous user message, other events to the user message of their turn
+update chat_events
+set chat_event_parent_uuid = (
+  select p.chat_event_uuid
+  from chat_events p
+  where
+    p.chat_id = chat_events.chat_id
+    and p.chat_event_kind = 'message.user'
+    and (p.chat_event_created_at, p.chat_event_id)
+      < (chat_events.chat_event_created_at, chat_events.chat_event_id)
+  order by p.chat_event_created_at desc, p.chat_event_id desc
+  limit 1
+);
+
+create index chat_events_chat_id_parent_uuid
+on chat_events (chat_id, chat_event_parent_uuid);
+
+-- the active branch of each chat is identified by its last user message
+alter table chats add column chat_active_event_uuid text;
+
+update chats
+set chat_active_event_uuid = (
+  select chat_event_uuid
+  from chat_events
+  where
+    chat_events.chat_id = chats.chat_id
+    and chat_event_kind = 'message.user'
+  order by chat_event_created_at desc, chat_event_id desc
+  limit 1
+);
@@ -0,0 +1,3 @@
+-- record where a forked chat was forked from
+alter table chats add column chat_forked_from_chat_id integer references chats (chat_id) on delete set null;
+alter table chats add column chat_forked_from_event_uuid text;
@@ -0,0 +1,2 @@
+-- allow archiving chats without deleting them
+alter table chats add column chat_archived boolean not null default false;
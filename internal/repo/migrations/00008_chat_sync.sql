@@ -0,0 +1,37 @@
+-- track when a chat or any of its events last changed
+alter table chats add column chat_updated_at text not null default '';
+
+update chats
+set chat_updated_at = coalesce(
+  (select max(chat_event_created_at) from chat_events where chat_events.chat_id = chats.chat_id),
+  chat_created_at
+);
+
+create index chats_updated_at on chats (chat_updated_at);
+
+create trigger chat_events_after_insert after insert on chat_events
+begin
+  update chats set chat_updated_at = max(chat_updated_at, new.chat_event_created_at) where chat_id = new.chat_id;
+end;
+
+create trigger chat_events_after_update after update on chat_events
+begin
+  update chats set chat_updated_at = max(chat_updated_at, new.chat_event_created_at) where chat_id = new.chat_id;
+end;
+
+create trigger chat_events_after_delete after delete on chat_events
+begin
+  update chats set chat_updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') where chat_id = old.chat_id;
+end;
+
+-- remember deleted chats so that clients can sync deletions
+create table chat_tombstones (
+  chat_id integer primary key,
+  chat_deleted_at text not null
+);
+
+create trigger chats_after_delete after delete on chats
+begin
+  insert or replace into chat_tombstones (chat_id, chat_deleted_at)
+  values (old.chat_id, strftime('%Y-%m-%dT%H:%M:%fZ', 'now'));
+end;
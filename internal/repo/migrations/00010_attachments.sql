@@ -0,0 +1,10 @@
+-- create the attachments table, the files themselves are stored in the data folder
+create table attachments (
+  attachment_id integer primary key,
+  attachment_created_at text not null,
+  attachment_uuid text not null,
+  attachment_name text not null,
+  attachment_media_type text not null,
+  attachment_size integer not null,
+  constraint unique_attachment_uuid unique (attachment_uuid)
+);
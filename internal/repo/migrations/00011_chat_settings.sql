@@ -0,0 +1,12 @@
+-- per-chat defaults for generations, null columns fall back to the client's parameters
+create table chat_settings (
+  chat_id integer primary key references chats (chat_id) on delete cascade,
+  chat_settings_updated_at text not null,
+  chat_settings_model_id text,
+  chat_settings_personality_id text,
+  chat_settings_system_prompt text,
+  chat_settings_temperature real,
+  chat_settings_max_tokens integer,
+  chat_settings_think boolean,
+  chat_settings_use_tools boolean
+);
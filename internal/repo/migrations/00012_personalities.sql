@@ -0,0 +1,12 @@
+-- personalities managed at runtime, an empty list of model IDs makes one available for every model
+create table personalities (
+  personality_id integer primary key,
+  personality_created_at text not null,
+  personality_updated_at text not null,
+  personality_uuid text not null,
+  personality_name text not null,
+  personality_system_prompt text not null,
+  personality_model_ids text not null default '[]',
+  constraint unique_personality_uuid unique (personality_uuid),
+  constraint check_valid_model_ids check (json_valid(personality_model_ids))
+);
@@ -0,0 +1,39 @@
+-- store every timestamp with exactly nine fractional digits, so that they compare correctly as text
+update chats set
+  chat_created_at = substr(chat_created_at, 1, 19) || '.' || substr(
+    case when substr(chat_created_at, 20, 1) = '.' then substr(chat_created_at, 21, length(chat_created_at) - 21) else '' end
+    || '000000000', 1, 9) || 'Z',
+  chat_updated_at = substr(chat_updated_at, 1, 19) || '.' || substr(
+    case when substr(chat_updated_at, 20, 1) = '.' then substr(chat_updated_at, 21, length(chat_updated_at) - 21) else '' end
+    || '000000000', 1, 9) || 'Z';
+
+update chat_events set
+  chat_event_created_at = substr(chat_event_created_at, 1, 19) || '.' || substr(
+    case when substr(chat_event_created_at, 20, 1) = '.' then substr(chat_event_created_at, 21, length(chat_event_created_at) - 21) else '' end
+    || '000000000', 1, 9) || 'Z';
+
+update chat_tombstones set
+  chat_deleted_at = substr(chat_deleted_at, 1, 19) || '.' || substr(
+    case when substr(chat_deleted_at, 20, 1) = '.' then substr(chat_deleted_at, 21, length(chat_deleted_at) - 21) else '' end
+    || '000000000', 1, 9) || 'Z';
+
+update api_keys set
+  api_key_created_at = substr(api_key_created_at, 1, 19) || '.' || substr(
+    case when substr(api_key_created_at, 20, 1) = '.' then substr(api_key_created_at, 21, length(api_key_created_at) - 21) else '' end
+    || '000000000', 1, 9) || 'Z',
+  api_key_expires_at = substr(api_key_expires_at, 1, 19) || '.' || substr(
+    case when substr(api_key_expires_at, 20, 1) = '.' then substr(api_key_expires_at, 21, length(api_key_expires_at) - 21) else '' end
+    || '000000000', 1, 9) || 'Z';
+
+drop trigger chat_events_after_delete;
+create trigger chat_events_after_delete after delete on chat_events
+begin
+  update chats set chat_updated_at = strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z' where chat_id = old.chat_id;
+end;
+
+drop trigger chats_after_delete;
+create trigger chats_after_delete after delete on chats
+begin
+  insert or replace into chat_tombstones (chat_id, chat_deleted_at)
+  values (old.chat_id, strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z');
+end;
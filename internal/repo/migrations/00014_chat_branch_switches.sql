@@ -0,0 +1,2 @@
+-- track when a chat last switched to an existing branch, whose older blocks clients have not synced
+alter table chats add column chat_branch_switched_at text not null default '';
@@ -17,6 +17,6 @@ func (r *Repository) CreateAPIKey(ctx context.Context, args CreateAPIKeyArgs) er
 	values (?, ?, ?)
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		now.Format(time.RFC3339Nano), now.Add(args.ExpiresIn).Format(time.RFC3339Nano), args.UUID)
+		now.Format(timeFormat), now.Add(args.ExpiresIn).Format(timeFormat), args.UUID)
 	return err
 }
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type CreateAttachmentArgs struct {
+	UUID      string
+	Name      string
+	MediaType string
+	Size      int64
+}
+
+func (r *Repository) CreateAttachment(ctx context.Context, args CreateAttachmentArgs) error {
+	var query = `
+	insert into attachments (
+		attachment_created_at, attachment_uuid, attachment_name, attachment_media_type, attachment_size
+	)
+	values (?, ?, ?, ?, ?)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		time.Now().UTC().Format(timeFormat), args.UUID, args.Name, args.MediaType, args.Size)
+	return err
+}
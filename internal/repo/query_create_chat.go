@@ -24,11 +24,12 @@ func (r *Repository) CreateChat(ctx context.Context, args CreateChatArgs) (int64
 		return 0, err
 	}
 	var createNewQuery = `
-	insert into chats (chat_created_at, chat_title, chat_pinned)
-	values (?, ?, ?)
+	insert into chats (chat_created_at, chat_updated_at, chat_title, chat_pinned)
+	values (?, ?, ?, ?)
 	`
+	now := time.Now().UTC().Format(timeFormat)
 	res, err := r.db.ExecContext(ctx, createNewQuery,
-		time.Now().UTC().Format(time.RFC3339Nano), args.Title, false)
+		now, now, args.Title, false)
 	if err != nil {
 		return 0, err
 	}
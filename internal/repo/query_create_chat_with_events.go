@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type ChatEvent struct {
+	CreatedAt  time.Time
+	ParentUUID string
+	UUID       string
+	Kind       string
+	Content    json.RawMessage
+}
+
+type CreateChatWithEventsArgs struct {
+	// CreatedAt defaults to now if not set.
+	CreatedAt           time.Time
+	Title               string
+	ActiveEventUUID     string
+	ForkedFromChatID    int64
+	ForkedFromEventUUID string
+	Events              []ChatEvent
+}
+
+// CreateChatWithEvents creates a new chat with the given events, all within a single transaction.
+// The events keep their timestamps.
+func (r *Repository) CreateChatWithEvents(ctx context.Context, args CreateChatWithEventsArgs) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var createChatQuery = `
+	insert into chats (
+		chat_created_at, chat_updated_at, chat_title, chat_pinned,
+		chat_active_event_uuid, chat_forked_from_chat_id, chat_forked_from_event_uuid
+	)
+	values (?, ?, ?, ?, nullif(?, ''), nullif(?, 0), nullif(?, ''))
+	`
+	now := time.Now().UTC()
+	createdAt := args.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	res, err := tx.ExecContext(ctx, createChatQuery,
+		createdAt.UTC().Format(timeFormat), now.Format(timeFormat), args.Title, false,
+		args.ActiveEventUUID, args.ForkedFromChatID, args.ForkedFromEventUUID)
+	if err != nil {
+		return 0, err
+	}
+	chatID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	var createEventQuery = `
+	insert into chat_events (
+		chat_id, chat_event_created_at, chat_event_parent_uuid, chat_event_uuid, chat_event_kind, chat_event_content
+	)
+	values (?, ?, nullif(?, ''), ?, ?, ?)
+	`
+	for _, event := range args.Events {
+		if _, err := tx.ExecContext(ctx, createEventQuery,
+			chatID, event.CreatedAt.UTC().Format(timeFormat),
+			event.ParentUUID, event.UUID, event.Kind, []byte(event.Content)); err != nil {
+			return 0, err
+		}
+	}
+	if r.search {
+		if _, err := tx.ExecContext(ctx, indexChatEventsQuery+`
+		and chat_id = ?
+		`, chatID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return chatID, nil
+}
@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type CreatePersonalityArgs struct {
+	UUID         string
+	Name         string
+	SystemPrompt string
+	// ModelIDs limits the personality to the models, or makes it available for every model if empty.
+	ModelIDs []string
+}
+
+func (r *Repository) CreatePersonality(ctx context.Context, args CreatePersonalityArgs) error {
+	var insertQuery = `
+	insert into personalities (
+		personality_created_at, personality_updated_at, personality_uuid, personality_name,
+		personality_system_prompt, personality_model_ids
+	)
+	values (?, ?, ?, ?, ?, ?)
+	`
+	modelIDs, err := json.Marshal(nonNil(args.ModelIDs))
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(timeFormat)
+	_, err = r.db.ExecContext(ctx, insertQuery,
+		now, now, args.UUID, args.Name, args.SystemPrompt, string(modelIDs))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"context"
+)
+
+type DeleteChatArgs struct {
+	ID int64
+}
+
+// DeleteChat deletes the chat along with all of its events and settings, which cascade.
+func (r *Repository) DeleteChat(ctx context.Context, args DeleteChatArgs) error {
+	var deleteQuery = `
+	delete from chats
+	where chat_id = ?
+	`
+	_, err := r.db.ExecContext(ctx, deleteQuery, args.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -2,21 +2,31 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 )
 
 type DeleteChatEventArgs struct {
-	ID string
+	ChatID int64
+	UUID   string
 }
 
+// DeleteChatEvent deletes the event of the chat, returning sql.ErrNoRows if it does not exist.
 func (r *Repository) DeleteChatEvent(ctx context.Context, args DeleteChatEventArgs) error {
 	var query = `
 	delete from chat_events
-	where chat_event_uuid = ?
+	where chat_id = ? and chat_event_uuid = ?
 	`
-	_, err := r.db.ExecContext(ctx, query,
-		args.ID)
+	res, err := r.db.ExecContext(ctx, query,
+		args.ChatID, args.UUID)
 	if err != nil {
 		return err
 	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestDeleteChatCascades(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	chatID, err := r.CreateChatWithEvents(ctx, CreateChatWithEventsArgs{
+		Title:           "Doomed",
+		ActiveEventUUID: "u1",
+		Events:          []ChatEvent{{UUID: "u1", Kind: "message.user", Content: json.RawMessage(`{"id":"u1"}`)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.UpdateChatSettings(ctx, UpdateChatSettingsArgs{ChatID: chatID, Settings: ChatSettings{ModelID: "m"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteChat(ctx, DeleteChatArgs{ID: chatID}); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"chats", "chat_events", "chat_settings"} {
+		var count int
+		if err := r.db.QueryRowContext(ctx, "select count(*) from "+table+" where chat_id = ?", chatID).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s has %d rows left for the chat", table, count)
+		}
+	}
+	var tombstones int
+	if err := r.db.QueryRowContext(ctx, "select count(*) from chat_tombstones where chat_id = ?", chatID).Scan(&tombstones); err != nil {
+		t.Fatal(err)
+	}
+	if tombstones != 1 {
+		t.Errorf("got %d tombstones, want 1", tombstones)
+	}
+}
@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DeletePersonalityArgs struct {
+	UUID string
+}
+
+// DeletePersonality deletes the personality, returning sql.ErrNoRows if it does not exist. Chats
+// using it as their default personality fall back to the client's choice.
+func (r *Repository) DeletePersonality(ctx context.Context, args DeletePersonalityArgs) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var deleteQuery = `
+	delete from personalities
+	where personality_uuid = ?
+	`
+	res, err := tx.ExecContext(ctx, deleteQuery, args.UUID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	var clearSettingsQuery = `
+	update chat_settings
+	set chat_settings_personality_id = null
+	where chat_settings_personality_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearSettingsQuery, args.UUID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type GetAttachmentArgs struct {
+	UUID string
+}
+
+type GetAttachmentResult struct {
+	CreatedAt time.Time
+	UUID      string
+	Name      string
+	MediaType string
+	Size      int64
+}
+
+func (r *Repository) GetAttachment(ctx context.Context, args GetAttachmentArgs) (GetAttachmentResult, error) {
+	var query = `
+	select attachment_created_at, attachment_uuid, attachment_name, attachment_media_type, attachment_size
+	from attachments
+	where attachment_uuid = ?
+	`
+	var (
+		item      GetAttachmentResult
+		createdAt string
+	)
+	if err := r.db.QueryRowContext(ctx, query, args.UUID).Scan(
+		&createdAt, &item.UUID, &item.Name, &item.MediaType, &item.Size,
+	); err != nil {
+		return GetAttachmentResult{}, err
+	}
+	var err error
+	item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return GetAttachmentResult{}, err
+	}
+	return item, nil
+}
@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttachments(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	before := time.Now().UTC()
+	args := CreateAttachmentArgs{UUID: "a1", Name: "notes.md", MediaType: "text/markdown", Size: 42}
+	if err := r.CreateAttachment(ctx, args); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.GetAttachment(ctx, GetAttachmentArgs{UUID: "a1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UUID != args.UUID || got.Name != args.Name || got.MediaType != args.MediaType || got.Size != args.Size {
+		t.Errorf("attachment = %+v, want %+v", got, args)
+	}
+	if got.CreatedAt.Before(before) || got.CreatedAt.After(time.Now()) {
+		t.Errorf("created at %v, want after %v", got.CreatedAt, before)
+	}
+	if err := r.CreateAttachment(ctx, CreateAttachmentArgs{UUID: "a1", Name: "other.md", MediaType: "text/markdown"}); err == nil {
+		t.Error("created a second attachment with the same UUID")
+	}
+	if _, err := r.GetAttachment(ctx, GetAttachmentArgs{UUID: "missing"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("error for a missing attachment = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type GetChatArgs struct {
+	ID int64
+}
+
+type GetChatResult struct {
+	ID               int64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Title            string
+	Pinned           bool
+	Archived         bool
+	ForkedFromChatID int64
+	ActiveEventUUID  string
+	// BranchSwitchedAt is the time of the last switch to an existing branch, if any.
+	BranchSwitchedAt time.Time
+}
+
+func (r *Repository) GetChat(ctx context.Context, args GetChatArgs) (GetChatResult, error) {
+	var query = `
+	select
+		chat_id, chat_created_at, chat_updated_at, chat_title, chat_pinned, chat_archived,
+		coalesce(chat_forked_from_chat_id, 0), coalesce(chat_active_event_uuid, ''), chat_branch_switched_at
+	from chats
+	where chat_id = ?
+	`
+	var (
+		item                                   GetChatResult
+		createdAt, updatedAt, branchSwitchedAt string
+	)
+	if err := r.db.QueryRowContext(ctx, query, args.ID).Scan(
+		&item.ID, &createdAt, &updatedAt, &item.Title, &item.Pinned, &item.Archived,
+		&item.ForkedFromChatID, &item.ActiveEventUUID, &branchSwitchedAt,
+	); err != nil {
+		return GetChatResult{}, err
+	}
+	var err error
+	item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return GetChatResult{}, err
+	}
+	item.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt)
+	if err != nil {
+		return GetChatResult{}, err
+	}
+	if branchSwitchedAt != "" {
+		item.BranchSwitchedAt, err = time.Parse(time.RFC3339Nano, branchSwitchedAt)
+		if err != nil {
+			return GetChatResult{}, err
+		}
+	}
+	return item, nil
+}
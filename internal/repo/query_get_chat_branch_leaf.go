@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+)
+
+type GetChatBranchLeafArgs struct {
+	ChatID int64
+	UUID   string
+}
+
+// GetChatBranchLeaf follows the most recent user message at each level below the given user message
+// and returns the last one.
+func (r *Repository) GetChatBranchLeaf(ctx context.Context, args GetChatBranchLeafArgs) (string, error) {
+	var query = `
+	with recursive leaf (uuid, depth) as (
+		select ?, 0
+		union all
+		select
+			(
+				select chat_event_uuid
+				from chat_events
+				where
+					chat_id = ?
+					and chat_event_parent_uuid = leaf.uuid
+					and chat_event_kind = 'message.user'
+				order by chat_event_created_at desc, chat_event_id desc
+				limit 1
+			),
+			depth + 1
+		from leaf
+		where leaf.uuid is not null
+	)
+	select uuid
+	from leaf
+	where uuid is not null
+	order by depth desc
+	limit 1
+	`
+	var uuid string
+	if err := r.db.QueryRowContext(ctx, query, args.UUID, args.ChatID).Scan(&uuid); err != nil {
+		return "", err
+	}
+	return uuid, nil
+}
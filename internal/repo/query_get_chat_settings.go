@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ChatSettings holds the defaults of a chat. Empty and nil fields are not set.
+type ChatSettings struct {
+	ModelID       string
+	PersonalityID string
+	SystemPrompt  string
+	Temperature   *float64
+	MaxTokens     int64
+	Think         *bool
+	UseTools      *bool
+}
+
+type GetChatSettingsArgs struct {
+	ChatID int64
+}
+
+// GetChatSettings returns the settings of the chat, or empty settings if none have been stored.
+func (r *Repository) GetChatSettings(ctx context.Context, args GetChatSettingsArgs) (ChatSettings, error) {
+	var query = `
+	select
+		coalesce(chat_settings_model_id, ''), coalesce(chat_settings_personality_id, ''),
+		coalesce(chat_settings_system_prompt, ''), chat_settings_temperature,
+		coalesce(chat_settings_max_tokens, 0), chat_settings_think, chat_settings_use_tools
+	from chat_settings
+	where chat_id = ?
+	`
+	var (
+		item        ChatSettings
+		temperature sql.NullFloat64
+		think       sql.NullBool
+		useTools    sql.NullBool
+	)
+	err := r.db.QueryRowContext(ctx, query, args.ChatID).Scan(
+		&item.ModelID, &item.PersonalityID, &item.SystemPrompt, &temperature,
+		&item.MaxTokens, &think, &useTools,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ChatSettings{}, nil
+	}
+	if err != nil {
+		return ChatSettings{}, err
+	}
+	if temperature.Valid {
+		item.Temperature = &temperature.Float64
+	}
+	if think.Valid {
+		item.Think = &think.Bool
+	}
+	if useTools.Valid {
+		item.UseTools = &useTools.Bool
+	}
+	return item, nil
+}
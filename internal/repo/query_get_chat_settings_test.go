@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestChatSettings(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	chatID := createTestChats(t, r, 1)[0]
+	settings, err := r.GetChatSettings(ctx, GetChatSettingsArgs{ChatID: chatID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(settings, ChatSettings{}) {
+		t.Errorf("settings before any are stored = %+v", settings)
+	}
+	var (
+		zero = 0.0
+		no   = false
+		yes  = true
+	)
+	tests := []struct {
+		name     string
+		settings ChatSettings
+	}{
+		{
+			name: "every field",
+			settings: ChatSettings{
+				ModelID: "m", PersonalityID: "p", SystemPrompt: "Be brief.",
+				Temperature: &zero, MaxTokens: 1024, Think: &no, UseTools: &yes,
+			},
+		},
+		{name: "fields are cleared when replaced", settings: ChatSettings{ModelID: "m"}},
+		{name: "nothing", settings: ChatSettings{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.UpdateChatSettings(ctx, UpdateChatSettingsArgs{ChatID: chatID, Settings: tt.settings}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.GetChatSettings(ctx, GetChatSettingsArgs{ChatID: chatID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// NOTE: zero temperatures and false flags are settings of their own, unlike unset ones
+			if !reflect.DeepEqual(got, tt.settings) {
+				t.Errorf("settings = %+v, want %+v", got, tt.settings)
+			}
+		})
+	}
+	if err := r.UpdateChatSettings(ctx, UpdateChatSettingsArgs{ChatID: chatID + 1, Settings: ChatSettings{ModelID: "m"}}); err == nil {
+		t.Error("stored settings for a chat which does not exist")
+	}
+}
@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// GetChatsWatermark returns the time of the latest change to any chat, including deletions, or the
+// zero time if there are no chats.
+func (r *Repository) GetChatsWatermark(ctx context.Context) (time.Time, error) {
+	var query = `
+	select coalesce(max(watermark), '')
+	from (
+		select max(chat_updated_at) as watermark from chats
+		union all
+		select max(chat_deleted_at) from chat_tombstones
+	)
+	`
+	var watermark string
+	if err := r.db.QueryRowContext(ctx, query).Scan(&watermark); err != nil {
+		return time.Time{}, err
+	}
+	if watermark == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, watermark)
+}
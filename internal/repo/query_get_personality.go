@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"context"
+)
+
+type GetPersonalityArgs struct {
+	UUID string
+}
+
+func (r *Repository) GetPersonality(ctx context.Context, args GetPersonalityArgs) (Personality, error) {
+	var query = `
+	select
+		personality_created_at, personality_updated_at, personality_uuid, personality_name,
+		personality_system_prompt, personality_model_ids
+	from personalities
+	where personality_uuid = ?
+	`
+	return scanPersonality(r.db.QueryRowContext(ctx, query, args.UUID))
+}
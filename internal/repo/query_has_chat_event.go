@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"context"
+)
+
+type HasChatEventArgs struct {
+	UUID string
+}
+
+func (r *Repository) HasChatEvent(ctx context.Context, args HasChatEventArgs) (bool, error) {
+	var query = `
+	select exists (select 1 from chat_events where chat_event_uuid = ?)
+	`
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, args.UUID).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"context"
+)
+
+// indexChatEventsQuery indexes the message events, narrowed down by any appended conditions.
+var indexChatEventsQuery = `
+insert into chat_search (rowid, chat_search_text, chat_id, chat_event_uuid)
+select chat_event_id, json_extract(chat_event_content, '$.content'), chat_id, chat_event_uuid
+from chat_events
+where
+	chat_event_kind in ('message.user', 'message.assistant')
+	and coalesce(json_extract(chat_event_content, '$.content'), '') != ''
+`
+
+type IndexChatEventArgs struct {
+	ChatID int64
+	UUID   string
+}
+
+// IndexChatEvent updates the search index of the message event with its current content. It does
+// nothing when search is not available.
+func (r *Repository) IndexChatEvent(ctx context.Context, args IndexChatEventArgs) error {
+	if !r.search {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var deleteQuery = `
+	delete from chat_search
+	where rowid = (select chat_event_id from chat_events where chat_id = ? and chat_event_uuid = ?)
+	`
+	if _, err := tx.ExecContext(ctx, deleteQuery, args.ChatID, args.UUID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, indexChatEventsQuery+`
+	and chat_id = ? and chat_event_uuid = ?
+	`, args.ChatID, args.UUID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
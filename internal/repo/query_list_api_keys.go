@@ -19,7 +19,7 @@ func (r *Repository) ListAPIKeys(ctx context.Context) (ListAPIKeysResult, error)
 	where api_key_expires_at < ?
 	`
 	_, err := r.db.ExecContext(ctx, deleteExpiredQuery,
-		time.Now().UTC().Format(time.RFC3339Nano))
+		time.Now().UTC().Format(timeFormat))
 	if err != nil {
 		return ListAPIKeysResult{}, err
 	}
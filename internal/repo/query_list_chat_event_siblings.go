@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type ListChatEventSiblingsArgs struct {
+	ChatID int64
+	UUID   string
+}
+
+type ListChatEventSiblingsResult struct {
+	Items []struct {
+		CreatedAt time.Time
+		UUID      string
+		BlockUUID string
+		Active    bool
+	}
+}
+
+// ListChatEventSiblings lists the user messages sharing the parent of the given user message, i.e. the
+// alternative turns at that point of the conversation, together with their text blocks.
+func (r *Repository) ListChatEventSiblings(
+	ctx context.Context, args ListChatEventSiblingsArgs,
+) (ListChatEventSiblingsResult, error) {
+	var query = `
+	with recursive branch (uuid) as (
+		select chat_active_event_uuid from chats where chat_id = ?
+		union all
+		select chat_events.chat_event_parent_uuid
+		from chat_events
+		join branch on chat_events.chat_event_uuid = branch.uuid
+		where chat_events.chat_event_parent_uuid is not null
+	)
+	select
+		s.chat_event_created_at,
+		s.chat_event_uuid,
+		coalesce((
+			select b.chat_event_uuid
+			from chat_events b
+			where
+				b.chat_id = s.chat_id
+				and b.chat_event_parent_uuid = s.chat_event_uuid
+				and b.chat_event_kind = 'block.text'
+				and json_extract(b.chat_event_content, '$.role') = 'user'
+			order by b.chat_event_created_at asc, b.chat_event_id asc
+			limit 1
+		), ''),
+		s.chat_event_uuid in (select uuid from branch)
+	from chat_events s
+	join chat_events e on e.chat_id = s.chat_id and e.chat_event_uuid = ?
+	where
+		s.chat_id = ?
+		and s.chat_event_kind = 'message.user'
+		and s.chat_event_parent_uuid is e.chat_event_parent_uuid
+	order by s.chat_event_created_at asc, s.chat_event_id asc
+	`
+	rows, err := r.db.QueryContext(ctx, query, args.ChatID, args.UUID, args.ChatID)
+	if err != nil {
+		return ListChatEventSiblingsResult{}, err
+	}
+	defer rows.Close()
+	items := make([]struct {
+		CreatedAt time.Time
+		UUID      string
+		BlockUUID string
+		Active    bool
+	}, 0)
+	for rows.Next() {
+		var createdAt string
+		var item struct {
+			CreatedAt time.Time
+			UUID      string
+			BlockUUID string
+			Active    bool
+		}
+		if err := rows.Scan(&createdAt, &item.UUID, &item.BlockUUID, &item.Active); err != nil {
+			return ListChatEventSiblingsResult{}, err
+		}
+		item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return ListChatEventSiblingsResult{}, err
+		}
+		items = append(items, item)
+	}
+	return ListChatEventSiblingsResult{items}, nil
+}
@@ -9,46 +9,71 @@ import (
 type ListChatEventsArgs struct {
 	ChatID     int64
 	KindPrefix string
+	// Branch is the last user message of the branch to walk, defaulting to the chat's active branch.
+	Branch      string
+	AllBranches bool
 }
 
 type ListChatEventsResult struct {
 	Items []struct {
-		CreatedAt time.Time
-		UUID      string
-		Kind      string
-		Content   json.RawMessage
+		CreatedAt  time.Time
+		ParentUUID string
+		UUID       string
+		Kind       string
+		Content    json.RawMessage
 	}
 }
 
 func (r *Repository) ListChatEvents(ctx context.Context, args ListChatEventsArgs) (ListChatEventsResult, error) {
 	var query = `
-	select chat_event_created_at, chat_event_uuid, chat_event_kind, chat_event_content
+	with recursive branch (uuid) as (
+		select coalesce(nullif(?, ''), (select chat_active_event_uuid from chats where chat_id = ?))
+		union all
+		select chat_events.chat_event_parent_uuid
+		from chat_events
+		join branch on chat_events.chat_event_uuid = branch.uuid
+		where chat_events.chat_event_parent_uuid is not null
+	)
+	select
+		chat_event_created_at,
+		coalesce(chat_event_parent_uuid, ''),
+		chat_event_uuid,
+		chat_event_kind,
+		chat_event_content
 	from chat_events
 	where
 		chat_id = ?
 		and chat_event_kind like ?
+		and (
+			?
+			or (chat_event_kind = 'message.user' and chat_event_uuid in (select uuid from branch))
+			or (chat_event_kind != 'message.user' and chat_event_parent_uuid in (select uuid from branch))
+		)
 	order by chat_event_created_at asc, chat_event_id asc
 	`
-	rows, err := r.db.QueryContext(ctx, query, args.ChatID, args.KindPrefix+"%")
+	rows, err := r.db.QueryContext(ctx, query,
+		args.Branch, args.ChatID, args.ChatID, args.KindPrefix+"%", args.AllBranches)
 	if err != nil {
 		return ListChatEventsResult{}, err
 	}
 	defer rows.Close()
 	items := make([]struct {
-		CreatedAt time.Time
-		UUID      string
-		Kind      string
-		Content   json.RawMessage
+		CreatedAt  time.Time
+		ParentUUID string
+		UUID       string
+		Kind       string
+		Content    json.RawMessage
 	}, 0)
 	for rows.Next() {
 		var createdAt string
 		var item struct {
-			CreatedAt time.Time
-			UUID      string
-			Kind      string
-			Content   json.RawMessage
+			CreatedAt  time.Time
+			ParentUUID string
+			UUID       string
+			Kind       string
+			Content    json.RawMessage
 		}
-		if err := rows.Scan(&createdAt, &item.UUID, &item.Kind, &item.Content); err != nil {
+		if err := rows.Scan(&createdAt, &item.ParentUUID, &item.UUID, &item.Kind, &item.Content); err != nil {
 			return ListChatEventsResult{}, err
 		}
 		item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
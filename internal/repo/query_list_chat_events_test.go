@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_fk=1", filepath.Join(t.TempDir(), "juttele.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return New(db)
+}
+
+func TestListChatEvents(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	// NOTE: the chat has two turns, and the third one was edited into two sibling branches
+	begin := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tree := []struct{ parent, uuid, kind string }{
+		{"", "u1", "message.user"},
+		{"u1", "b1", "block.text"},
+		{"u1", "m1", "message.assistant"},
+		{"u1", "u2", "message.user"},
+		{"u2", "m2", "message.assistant"},
+		{"u2", "u3a", "message.user"},
+		{"u3a", "m3a", "message.assistant"},
+		{"u2", "u3b", "message.user"},
+		{"u3b", "m3b", "message.assistant"},
+	}
+	events := make([]ChatEvent, 0, len(tree))
+	for idx, i := range tree {
+		events = append(events, ChatEvent{
+			CreatedAt:  begin.Add(time.Duration(idx) * time.Millisecond),
+			ParentUUID: i.parent,
+			UUID:       i.uuid,
+			Kind:       i.kind,
+			Content:    json.RawMessage(fmt.Sprintf(`{"id":%q}`, i.uuid)),
+		})
+	}
+	chatID, err := r.CreateChatWithEvents(ctx, CreateChatWithEventsArgs{
+		Title: "Branches", ActiveEventUUID: "u3b", Events: events,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherChatID, err := r.CreateChatWithEvents(ctx, CreateChatWithEventsArgs{
+		Title: "Other", Events: []ChatEvent{{CreatedAt: begin, UUID: "x1", Kind: "message.user", Content: json.RawMessage(`{}`)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args ListChatEventsArgs
+		want []string
+	}{
+		{
+			name: "active branch",
+			args: ListChatEventsArgs{ChatID: chatID},
+			want: []string{"u1", "b1", "m1", "u2", "m2", "u3b", "m3b"},
+		},
+		{
+			name: "other branch",
+			args: ListChatEventsArgs{ChatID: chatID, Branch: "u3a"},
+			want: []string{"u1", "b1", "m1", "u2", "m2", "u3a", "m3a"},
+		},
+		{
+			name: "branch ending before the fork",
+			args: ListChatEventsArgs{ChatID: chatID, Branch: "u2"},
+			want: []string{"u1", "b1", "m1", "u2", "m2"},
+		},
+		{
+			name: "kind prefix",
+			args: ListChatEventsArgs{ChatID: chatID, KindPrefix: "message.user"},
+			want: []string{"u1", "u2", "u3b"},
+		},
+		{
+			name: "all branches",
+			args: ListChatEventsArgs{ChatID: chatID, AllBranches: true},
+			want: []string{"u1", "b1", "m1", "u2", "m2", "u3a", "m3a", "u3b", "m3b"},
+		},
+		{
+			name: "unknown branch",
+			args: ListChatEventsArgs{ChatID: chatID, Branch: "missing"},
+			want: []string{},
+		},
+		{
+			name: "branch of another chat",
+			args: ListChatEventsArgs{ChatID: otherChatID, Branch: "u3a"},
+			want: []string{},
+		},
+		{
+			name: "chat without an active branch",
+			args: ListChatEventsArgs{ChatID: otherChatID},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := r.ListChatEvents(ctx, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(res.Items))
+			for _, i := range res.Items {
+				got = append(got, i.UUID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type ListChatTombstonesArgs struct {
+	Since time.Time
+}
+
+type ListChatTombstonesResult struct {
+	Items []struct {
+		ID        int64
+		DeletedAt time.Time
+	}
+}
+
+// ListChatTombstones lists the chats deleted after the given time.
+func (r *Repository) ListChatTombstones(
+	ctx context.Context, args ListChatTombstonesArgs,
+) (ListChatTombstonesResult, error) {
+	var query = `
+	select chat_id, chat_deleted_at
+	from chat_tombstones
+	where chat_deleted_at > ?
+	order by chat_deleted_at asc
+	`
+	rows, err := r.db.QueryContext(ctx, query,
+		args.Since.UTC().Format(timeFormat))
+	if err != nil {
+		return ListChatTombstonesResult{}, err
+	}
+	defer rows.Close()
+	items := make([]struct {
+		ID        int64
+		DeletedAt time.Time
+	}, 0)
+	for rows.Next() {
+		var deletedAt string
+		var item struct {
+			ID        int64
+			DeletedAt time.Time
+		}
+		if err := rows.Scan(&item.ID, &deletedAt); err != nil {
+			return ListChatTombstonesResult{}, err
+		}
+		item.DeletedAt, err = time.Parse(time.RFC3339Nano, deletedAt)
+		if err != nil {
+			return ListChatTombstonesResult{}, err
+		}
+		items = append(items, item)
+	}
+	return ListChatTombstonesResult{items}, nil
+}
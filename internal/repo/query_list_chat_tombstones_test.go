@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestChatUpdatedAt(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	ids := createTestChats(t, r, 1)
+	chatID := ids[0]
+	updatedAt := func() time.Time {
+		t.Helper()
+		chat, err := r.GetChat(ctx, GetChatArgs{ID: chatID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// NOTE: timestamps are compared as text, so they must all have the same width
+		var raw string
+		if err := r.db.QueryRowContext(ctx, `select chat_updated_at from chats where chat_id = ?`, chatID).Scan(&raw); err != nil {
+			t.Fatal(err)
+		}
+		if len(raw) != len(timeFormat) {
+			t.Errorf("chat_updated_at = %q, want the width of %q", raw, timeFormat)
+		}
+		return chat.UpdatedAt
+	}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "event created", run: func() error {
+			_, err := r.CreateChatEvent(ctx, CreateChatEventArgs{
+				ChatID: chatID, ParentUUID: "u0", UUID: "a0", Kind: "message.assistant", Content: json.RawMessage(`{}`),
+			})
+			return err
+		}},
+		{name: "event updated", run: func() error {
+			_, err := r.CreateChatEvent(ctx, CreateChatEventArgs{
+				ChatID: chatID, ParentUUID: "u0", UUID: "a0", Kind: "message.assistant", Content: json.RawMessage(`{"content":"hi"}`),
+			})
+			return err
+		}},
+		{name: "event deleted", run: func() error {
+			return r.DeleteChatEvent(ctx, DeleteChatEventArgs{ChatID: chatID, UUID: "a0"})
+		}},
+		{name: "renamed", run: func() error {
+			return r.UpdateChat(ctx, UpdateChatArgs{ID: chatID, Title: "Renamed"})
+		}},
+		{name: "branch updated", run: func() error {
+			return r.UpdateChatBranch(ctx, UpdateChatBranchArgs{ID: chatID, EventUUID: "u0"})
+		}},
+	}
+	last := updatedAt()
+	for _, step := range steps {
+		time.Sleep(time.Millisecond)
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		current := updatedAt()
+		if !current.After(last) {
+			t.Errorf("%s: updated at %v, not after %v", step.name, current, last)
+		}
+		last = current
+	}
+	chat, err := r.GetChat(ctx, GetChatArgs{ID: chatID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !chat.BranchSwitchedAt.IsZero() {
+		t.Errorf("branch switched at %v without a switch", chat.BranchSwitchedAt)
+	}
+	if err := r.UpdateChatBranch(ctx, UpdateChatBranchArgs{ID: chatID, EventUUID: "u0", Switch: true}); err != nil {
+		t.Fatal(err)
+	}
+	chat, err = r.GetChat(ctx, GetChatArgs{ID: chatID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !chat.BranchSwitchedAt.Equal(chat.UpdatedAt) {
+		t.Errorf("branch switched at %v, want %v", chat.BranchSwitchedAt, chat.UpdatedAt)
+	}
+}
+
+func TestChatTombstones(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	watermark, err := r.GetChatsWatermark(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !watermark.IsZero() {
+		t.Errorf("watermark without chats = %v", watermark)
+	}
+	ids := createTestChats(t, r, 2)
+	kept, err := r.GetChat(ctx, GetChatArgs{ID: ids[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watermark, err = r.GetChatsWatermark(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if watermark.Before(kept.UpdatedAt) {
+		t.Errorf("watermark %v is before the latest change at %v", watermark, kept.UpdatedAt)
+	}
+
+	before := time.Now().UTC()
+	time.Sleep(time.Millisecond)
+	if err := r.DeleteChat(ctx, DeleteChatArgs{ID: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	tombstones, err := r.ListChatTombstones(ctx, ListChatTombstonesArgs{Since: before})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tombstones.Items) != 1 || tombstones.Items[0].ID != ids[0] || tombstones.Items[0].DeletedAt.Before(before) {
+		t.Fatalf("tombstones since %v = %+v, want chat %d", before, tombstones.Items, ids[0])
+	}
+	deletedAt := tombstones.Items[0].DeletedAt
+	if watermark, err = r.GetChatsWatermark(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !watermark.Equal(deletedAt) {
+		t.Errorf("watermark = %v, want the deletion at %v", watermark, deletedAt)
+	}
+	tombstones, err = r.ListChatTombstones(ctx, ListChatTombstonesArgs{Since: deletedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tombstones.Items) != 0 {
+		t.Errorf("tombstones since the deletion = %+v", tombstones.Items)
+	}
+}
@@ -5,44 +5,90 @@ import (
 	"time"
 )
 
+type ListChatsArgs struct {
+	// Since limits the chats to those changed after the given time, if set.
+	Since time.Time
+	// Limit caps the number of chats returned, if set.
+	Limit int
+	// AfterPinned and AfterID are the position of the last chat of the previous page, if any.
+	AfterPinned bool
+	AfterID     int64
+}
+
 type ListChatsResult struct {
 	Items []struct {
-		ID        int64
-		CreatedAt time.Time
-		Title     string
+		ID               int64
+		CreatedAt        time.Time
+		UpdatedAt        time.Time
+		Title            string
+		Pinned           bool
+		Archived         bool
+		ForkedFromChatID int64
 	}
 }
 
-func (r *Repository) ListChats(ctx context.Context) (ListChatsResult, error) {
+// ListChats lists chats with pinned chats first, newest first otherwise.
+func (r *Repository) ListChats(ctx context.Context, args ListChatsArgs) (ListChatsResult, error) {
 	var query = `
-	select chat_id, chat_created_at, chat_title
+	select
+		chat_id, chat_created_at, chat_updated_at, chat_title, chat_pinned, chat_archived,
+		coalesce(chat_forked_from_chat_id, 0)
 	from chats
-	order by chat_created_at asc
+	where
+		(? = '' or chat_updated_at > ?)
+		and (? = 0 or chat_pinned < ? or (chat_pinned = ? and chat_id < ?))
+	order by chat_pinned desc, chat_id desc
+	limit ?
 	`
-	rows, err := r.db.QueryContext(ctx, query)
+	var since string
+	if !args.Since.IsZero() {
+		since = args.Since.UTC().Format(timeFormat)
+	}
+	limit := -1
+	if args.Limit > 0 {
+		limit = args.Limit
+	}
+	rows, err := r.db.QueryContext(ctx, query,
+		since, since,
+		args.AfterID, args.AfterPinned, args.AfterPinned, args.AfterID,
+		limit)
 	if err != nil {
 		return ListChatsResult{}, err
 	}
 	defer rows.Close()
 	items := make([]struct {
-		ID        int64
-		CreatedAt time.Time
-		Title     string
+		ID               int64
+		CreatedAt        time.Time
+		UpdatedAt        time.Time
+		Title            string
+		Pinned           bool
+		Archived         bool
+		ForkedFromChatID int64
 	}, 0)
 	for rows.Next() {
-		var createdAt string
+		var createdAt, updatedAt string
 		var item struct {
-			ID        int64
-			CreatedAt time.Time
-			Title     string
+			ID               int64
+			CreatedAt        time.Time
+			UpdatedAt        time.Time
+			Title            string
+			Pinned           bool
+			Archived         bool
+			ForkedFromChatID int64
 		}
-		if err := rows.Scan(&item.ID, &createdAt, &item.Title); err != nil {
+		if err := rows.Scan(
+			&item.ID, &createdAt, &updatedAt, &item.Title, &item.Pinned, &item.Archived, &item.ForkedFromChatID,
+		); err != nil {
 			return ListChatsResult{}, err
 		}
 		item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
 		if err != nil {
 			return ListChatsResult{}, err
 		}
+		item.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt)
+		if err != nil {
+			return ListChatsResult{}, err
+		}
 		items = append(items, item)
 	}
 	return ListChatsResult{items}, nil
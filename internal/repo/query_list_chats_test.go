@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func createTestChats(t *testing.T, r *Repository, n int) []int64 {
+	t.Helper()
+	ids := make([]int64, 0, n)
+	for idx := range n {
+		uuid := fmt.Sprintf("u%d", idx)
+		id, err := r.CreateChatWithEvents(context.Background(), CreateChatWithEventsArgs{
+			Title:           fmt.Sprintf("Chat %d", idx),
+			ActiveEventUUID: uuid,
+			Events:          []ChatEvent{{UUID: uuid, Kind: "message.user", Content: json.RawMessage(`{}`)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestListChatsPinnedAndArchived(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	ids := createTestChats(t, r, 5)
+	before, err := r.GetChat(ctx, GetChatArgs{ID: ids[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{ids[1], ids[3]} {
+		if err := r.UpdateChatPinned(ctx, UpdateChatPinnedArgs{ID: id, Pinned: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.UpdateChatArchived(ctx, UpdateChatArchivedArgs{ID: ids[2], Archived: true}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := r.GetChat(ctx, GetChatArgs{ID: ids[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.Pinned || after.Archived || !after.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("pinned chat = %+v, updated before at %v", after, before.UpdatedAt)
+	}
+
+	// NOTE: pinned chats come first, newest first within both groups, and archived chats are listed too
+	want := []int64{ids[3], ids[1], ids[4], ids[2], ids[0]}
+	for _, limit := range []int{1, 2, 3, 5, 10} {
+		t.Run(fmt.Sprintf("pages of %d", limit), func(t *testing.T) {
+			var (
+				got  []int64
+				args = ListChatsArgs{Limit: limit}
+			)
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatalf("paging did not end, got %v", got)
+				}
+				res, err := r.ListChats(ctx, args)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, i := range res.Items {
+					got = append(got, i.ID)
+					if wantPinned := i.ID == ids[1] || i.ID == ids[3]; i.Pinned != wantPinned {
+						t.Errorf("chat %d: pinned = %t", i.ID, i.Pinned)
+					}
+					if wantArchived := i.ID == ids[2]; i.Archived != wantArchived {
+						t.Errorf("chat %d: archived = %t", i.ID, i.Archived)
+					}
+				}
+				if len(res.Items) < limit {
+					break
+				}
+				last := res.Items[len(res.Items)-1]
+				args.AfterPinned, args.AfterID = last.Pinned, last.ID
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("chats = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// NOTE: unpinning moves the chat back among the others
+	if err := r.UpdateChatPinned(ctx, UpdateChatPinnedArgs{ID: ids[3], Pinned: false}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.ListChats(ctx, ListChatsArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, i := range res.Items {
+		got = append(got, i.ID)
+	}
+	if want := []int64{ids[1], ids[4], ids[3], ids[2], ids[0]}; !slices.Equal(got, want) {
+		t.Errorf("after unpinning, chats = %v, want %v", got, want)
+	}
+}
+
+func TestListChatsSince(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	ids := createTestChats(t, r, 3)
+	since := time.Now().UTC()
+	time.Sleep(time.Millisecond)
+	if err := r.UpdateChatArchived(ctx, UpdateChatArchivedArgs{ID: ids[0], Archived: true}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.ListChats(ctx, ListChatsArgs{Since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].ID != ids[0] || !res.Items[0].Archived {
+		t.Errorf("chats changed since %v = %+v, want only the archived chat %d", since, res.Items, ids[0])
+	}
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type Personality struct {
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UUID         string
+	Name         string
+	SystemPrompt string
+	ModelIDs     []string
+}
+
+type ListPersonalitiesResult struct {
+	Items []Personality
+}
+
+func (r *Repository) ListPersonalities(ctx context.Context) (ListPersonalitiesResult, error) {
+	var listQuery = `
+	select
+		personality_created_at, personality_updated_at, personality_uuid, personality_name,
+		personality_system_prompt, personality_model_ids
+	from personalities
+	order by personality_id
+	`
+	rows, err := r.db.QueryContext(ctx, listQuery)
+	if err != nil {
+		return ListPersonalitiesResult{}, err
+	}
+	defer rows.Close()
+	items := make([]Personality, 0)
+	for rows.Next() {
+		item, err := scanPersonality(rows)
+		if err != nil {
+			return ListPersonalitiesResult{}, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return ListPersonalitiesResult{}, err
+	}
+	return ListPersonalitiesResult{items}, nil
+}
+
+func scanPersonality(row interface{ Scan(...any) error }) (Personality, error) {
+	var (
+		item                 Personality
+		createdAt, updatedAt string
+		modelIDs             string
+	)
+	if err := row.Scan(
+		&createdAt, &updatedAt, &item.UUID, &item.Name, &item.SystemPrompt, &modelIDs,
+	); err != nil {
+		return Personality{}, err
+	}
+	var err error
+	item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Personality{}, err
+	}
+	item.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt)
+	if err != nil {
+		return Personality{}, err
+	}
+	if err := json.Unmarshal([]byte(modelIDs), &item.ModelIDs); err != nil {
+		return Personality{}, err
+	}
+	return item, nil
+}
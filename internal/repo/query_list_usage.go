@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"context"
+)
+
+type ListUsageArgs struct {
+	ChatID int64
+}
+
+type ListUsageResult struct {
+	Items []struct {
+		ChatID           int64
+		ModelID          string
+		ModelName        string
+		Messages         int64
+		InputTokens      int64
+		OutputTokens     int64
+		CachedTokens     int64
+		CacheWriteTokens int64
+		ReasoningTokens  int64
+		Cost             float64
+	}
+}
+
+func (r *Repository) ListUsage(ctx context.Context, args ListUsageArgs) (ListUsageResult, error) {
+	var query = `
+	select
+		chat_id,
+		coalesce(json_extract(chat_event_content, '$.meta.model_id'), '') as model_id,
+		coalesce(max(json_extract(chat_event_content, '$.meta.model_name')), ''),
+		count(*),
+		coalesce(sum(json_extract(chat_event_content, '$.usage.input_tokens')), 0),
+		coalesce(sum(json_extract(chat_event_content, '$.usage.output_tokens')), 0),
+		coalesce(sum(json_extract(chat_event_content, '$.usage.cached_tokens')), 0),
+		coalesce(sum(json_extract(chat_event_content, '$.usage.cache_write_tokens')), 0),
+		coalesce(sum(json_extract(chat_event_content, '$.usage.reasoning_tokens')), 0),
+		coalesce(sum(cast(json_extract(chat_event_content, '$.meta.cost') as real)), 0)
+	from chat_events
+	where
+		chat_event_kind = 'message.assistant'
+		and json_extract(chat_event_content, '$.usage') is not null
+		and (? = 0 or chat_id = ?)
+	group by chat_id, model_id
+	order by chat_id asc, model_id asc
+	`
+	rows, err := r.db.QueryContext(ctx, query, args.ChatID, args.ChatID)
+	if err != nil {
+		return ListUsageResult{}, err
+	}
+	defer rows.Close()
+	items := make([]struct {
+		ChatID           int64
+		ModelID          string
+		ModelName        string
+		Messages         int64
+		InputTokens      int64
+		OutputTokens     int64
+		CachedTokens     int64
+		CacheWriteTokens int64
+		ReasoningTokens  int64
+		Cost             float64
+	}, 0)
+	for rows.Next() {
+		var item struct {
+			ChatID           int64
+			ModelID          string
+			ModelName        string
+			Messages         int64
+			InputTokens      int64
+			OutputTokens     int64
+			CachedTokens     int64
+			CacheWriteTokens int64
+			ReasoningTokens  int64
+			Cost             float64
+		}
+		if err := rows.Scan(
+			&item.ChatID, &item.ModelID, &item.ModelName, &item.Messages,
+			&item.InputTokens, &item.OutputTokens, &item.CachedTokens, &item.CacheWriteTokens, &item.ReasoningTokens,
+			&item.Cost,
+		); err != nil {
+			return ListUsageResult{}, err
+		}
+		items = append(items, item)
+	}
+	return ListUsageResult{items}, nil
+}
@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// SearchChatsHighlightStart and SearchChatsHighlightEnd delimit the matched terms in snippets.
+const (
+	SearchChatsHighlightStart = "\x01"
+	SearchChatsHighlightEnd   = "\x02"
+)
+
+type SearchChatsArgs struct {
+	// Query is plain text, every term of which must match. The last term matches as a prefix.
+	Query string
+	Limit int
+}
+
+type SearchChatsResult struct {
+	Items []struct {
+		ChatID        int64
+		ChatCreatedAt time.Time
+		ChatTitle     string
+		// EventUUID is the matching message event, or empty if the chat's title matched.
+		EventUUID string
+		Snippet   string
+	}
+}
+
+// SearchChats lists the best matches for the query across chat titles and messages.
+func (r *Repository) SearchChats(ctx context.Context, args SearchChatsArgs) (SearchChatsResult, error) {
+	var query = `
+	select
+		chats.chat_id,
+		chats.chat_created_at,
+		chats.chat_title,
+		chat_search.chat_event_uuid,
+		snippet(chat_search, 0, ?, ?, '…', 16)
+	from chat_search
+	join chats on chats.chat_id = chat_search.chat_id
+	where chat_search match ?
+	order by bm25(chat_search) asc
+	limit ?
+	`
+	match := searchChatsMatch(args.Query)
+	if match == "" {
+		return SearchChatsResult{}, nil
+	}
+	limit := -1
+	if args.Limit > 0 {
+		limit = args.Limit
+	}
+	rows, err := r.db.QueryContext(ctx, query,
+		SearchChatsHighlightStart, SearchChatsHighlightEnd, match, limit)
+	if err != nil {
+		return SearchChatsResult{}, err
+	}
+	defer rows.Close()
+	items := make([]struct {
+		ChatID        int64
+		ChatCreatedAt time.Time
+		ChatTitle     string
+		EventUUID     string
+		Snippet       string
+	}, 0)
+	for rows.Next() {
+		var createdAt string
+		var item struct {
+			ChatID        int64
+			ChatCreatedAt time.Time
+			ChatTitle     string
+			EventUUID     string
+			Snippet       string
+		}
+		if err := rows.Scan(&item.ChatID, &createdAt, &item.ChatTitle, &item.EventUUID, &item.Snippet); err != nil {
+			return SearchChatsResult{}, err
+		}
+		item.ChatCreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return SearchChatsResult{}, err
+		}
+		items = append(items, item)
+	}
+	return SearchChatsResult{items}, nil
+}
+
+// searchChatsMatch quotes every term of the plain text query so that FTS5 syntax is never
+// interpreted, with the last term matching as a prefix for search-as-you-type.
+func searchChatsMatch(query string) string {
+	terms := strings.Fields(query)
+	for idx, term := range terms {
+		terms[idx] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	if len(terms) > 0 {
+		terms[len(terms)-1] += "*"
+	}
+	return strings.Join(terms, " ")
+}
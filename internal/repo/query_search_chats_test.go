@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSearchChatsMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: ""},
+		{query: "   ", want: ""},
+		{query: "bread", want: `"bread"*`},
+		{query: " sourdough  bre ", want: `"sourdough" "bre"*`},
+		{query: `say "hi" OR NOT`, want: `"say" """hi""" "OR" "NOT"*`},
+		{query: "col:umn*", want: `"col:umn*"*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := searchChatsMatch(tt.query); got != tt.want {
+				t.Errorf("searchChatsMatch(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchChats(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	chatID, err := r.CreateChatWithEvents(ctx, CreateChatWithEventsArgs{
+		Title:           "Weekend plans",
+		ActiveEventUUID: "u1",
+		Events: []ChatEvent{
+			{UUID: "u1", Kind: "message.user", Content: json.RawMessage(`{"id":"u1","content":"How do I bake sourdough bread?"}`)},
+			{ParentUUID: "u1", UUID: "b1", Kind: "block.text", Content: json.RawMessage(`{"id":"b1","content":"sourdough"}`)},
+			{ParentUUID: "u1", UUID: "a1", Kind: "message.assistant", Content: json.RawMessage(`{"id":"a1","content":"Start with a starter."}`)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: setting up search indexes the chats and messages created before it
+	available, err := r.SetupSearch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !available {
+		t.Skip("SQLite was built without FTS5")
+	}
+	search := func(query string) []string {
+		t.Helper()
+		res, err := r.SearchChats(ctx, SearchChatsArgs{Query: query})
+		if err != nil {
+			t.Fatalf("searching for %q: %v", query, err)
+		}
+		matches := make([]string, 0, len(res.Items))
+		for _, i := range res.Items {
+			if i.ChatID != chatID {
+				t.Errorf("match in chat %d, want %d", i.ChatID, chatID)
+			}
+			if i.EventUUID == "" {
+				matches = append(matches, "title:"+i.ChatTitle)
+				continue
+			}
+			matches = append(matches, i.EventUUID)
+		}
+		slices.Sort(matches)
+		return matches
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "sourdough", want: []string{"u1"}},
+		{query: "sourdough bre", want: []string{"u1"}},
+		{query: "start", want: []string{"a1"}},
+		{query: "weekend", want: []string{"title:Weekend plans"}},
+		{query: "plan", want: []string{"title:Weekend plans"}},
+		{query: `"bread OR`, want: []string{}},
+		{query: "pizza", want: []string{}},
+	}
+	for _, tt := range tests {
+		if got := search(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// NOTE: renamed titles are reindexed by the triggers and messages explicitly
+	if err := r.UpdateChat(ctx, UpdateChatArgs{ID: chatID, Title: "Baking bread"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("weekend"); len(got) != 0 {
+		t.Errorf("old title still matches: %v", got)
+	}
+	if got, want := search("baking"), []string{"title:Baking bread"}; !slices.Equal(got, want) {
+		t.Errorf("search(baking) = %v, want %v", got, want)
+	}
+	if _, err := r.CreateChatEvent(ctx, CreateChatEventArgs{
+		ChatID: chatID, ParentUUID: "u1", UUID: "a1", Kind: "message.assistant",
+		Content: json.RawMessage(`{"id":"a1","content":"Feed the levain first."}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := search("levain"), []string{}; !slices.Equal(got, want) {
+		t.Errorf("unindexed update matches: %v", got)
+	}
+	if err := r.IndexChatEvent(ctx, IndexChatEventArgs{ChatID: chatID, UUID: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := search("levain"), []string{"a1"}; !slices.Equal(got, want) {
+		t.Errorf("search(levain) = %v, want %v", got, want)
+	}
+	if got := search("starter"); len(got) != 0 {
+		t.Errorf("previous content still matches: %v", got)
+	}
+
+	if err := r.DeleteChat(ctx, DeleteChatArgs{ID: chatID}); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"baking", "sourdough", "levain"} {
+		if got := search(query); len(got) != 0 {
+			t.Errorf("deleted chat matches %q: %v", query, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"time"
 )
 
 type UpdateChatArgs struct {
@@ -12,11 +13,11 @@ type UpdateChatArgs struct {
 func (r *Repository) UpdateChat(ctx context.Context, args UpdateChatArgs) error {
 	var updateQuery = `
 	update chats
-	set chat_title = ?
+	set chat_title = ?, chat_updated_at = ?
 	where chat_id = ?
 	`
 	_, err := r.db.ExecContext(ctx, updateQuery,
-		args.Title, args.ID)
+		args.Title, time.Now().UTC().Format(timeFormat), args.ID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type UpdateChatArchivedArgs struct {
+	ID       int64
+	Archived bool
+}
+
+func (r *Repository) UpdateChatArchived(ctx context.Context, args UpdateChatArchivedArgs) error {
+	var updateQuery = `
+	update chats
+	set chat_archived = ?, chat_updated_at = ?
+	where chat_id = ?
+	`
+	_, err := r.db.ExecContext(ctx, updateQuery,
+		args.Archived, time.Now().UTC().Format(timeFormat), args.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type UpdateChatBranchArgs struct {
+	ID        int64
+	EventUUID string
+	// Switch marks the change as a switch to an existing branch, which clients must sync in full.
+	Switch bool
+}
+
+func (r *Repository) UpdateChatBranch(ctx context.Context, args UpdateChatBranchArgs) error {
+	var updateQuery = `
+	update chats
+	set
+		chat_active_event_uuid = ?,
+		chat_updated_at = ?,
+		chat_branch_switched_at = case when ? then ? else chat_branch_switched_at end
+	where chat_id = ?
+	`
+	now := time.Now().UTC().Format(timeFormat)
+	_, err := r.db.ExecContext(ctx, updateQuery,
+		args.EventUUID, now, args.Switch, now, args.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type UpdateChatPinnedArgs struct {
+	ID     int64
+	Pinned bool
+}
+
+func (r *Repository) UpdateChatPinned(ctx context.Context, args UpdateChatPinnedArgs) error {
+	var updateQuery = `
+	update chats
+	set chat_pinned = ?, chat_updated_at = ?
+	where chat_id = ?
+	`
+	_, err := r.db.ExecContext(ctx, updateQuery,
+		args.Pinned, time.Now().UTC().Format(timeFormat), args.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
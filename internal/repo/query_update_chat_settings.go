@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type UpdateChatSettingsArgs struct {
+	ChatID   int64
+	Settings ChatSettings
+}
+
+// UpdateChatSettings replaces the settings of the chat.
+func (r *Repository) UpdateChatSettings(ctx context.Context, args UpdateChatSettingsArgs) error {
+	var upsertQuery = `
+	insert into chat_settings (
+		chat_id, chat_settings_updated_at, chat_settings_model_id, chat_settings_personality_id,
+		chat_settings_system_prompt, chat_settings_temperature, chat_settings_max_tokens,
+		chat_settings_think, chat_settings_use_tools
+	)
+	values (?, ?, nullif(?, ''), nullif(?, ''), nullif(?, ''), ?, nullif(?, 0), ?, ?)
+	on conflict (chat_id) do update set
+		chat_settings_updated_at = excluded.chat_settings_updated_at,
+		chat_settings_model_id = excluded.chat_settings_model_id,
+		chat_settings_personality_id = excluded.chat_settings_personality_id,
+		chat_settings_system_prompt = excluded.chat_settings_system_prompt,
+		chat_settings_temperature = excluded.chat_settings_temperature,
+		chat_settings_max_tokens = excluded.chat_settings_max_tokens,
+		chat_settings_think = excluded.chat_settings_think,
+		chat_settings_use_tools = excluded.chat_settings_use_tools
+	`
+	s := args.Settings
+	_, err := r.db.ExecContext(ctx, upsertQuery,
+		args.ChatID, time.Now().UTC().Format(timeFormat), s.ModelID, s.PersonalityID,
+		s.SystemPrompt, s.Temperature, s.MaxTokens, s.Think, s.UseTools)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type UpdatePersonalityArgs struct {
+	UUID         string
+	Name         string
+	SystemPrompt string
+	ModelIDs     []string
+}
+
+// UpdatePersonality replaces the personality, returning sql.ErrNoRows if it does not exist.
+func (r *Repository) UpdatePersonality(ctx context.Context, args UpdatePersonalityArgs) error {
+	var updateQuery = `
+	update personalities
+	set
+		personality_updated_at = ?, personality_name = ?, personality_system_prompt = ?,
+		personality_model_ids = ?
+	where personality_uuid = ?
+	`
+	modelIDs, err := json.Marshal(nonNil(args.ModelIDs))
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, updateQuery,
+		time.Now().UTC().Format(timeFormat), args.Name, args.SystemPrompt, string(modelIDs), args.UUID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
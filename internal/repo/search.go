@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+)
+
+// searchTriggers are the triggers keeping the search index of chat titles in sync. Messages are
+// indexed explicitly with IndexChatEvent, so that streamed answers are indexed only once finished.
+var searchTriggers = []string{
+	`create trigger chat_search_chats_after_insert after insert on chats
+	begin
+		insert into chat_search (rowid, chat_search_text, chat_id, chat_event_uuid)
+		values (-new.chat_id, new.chat_title, new.chat_id, '');
+	end`,
+	`create trigger chat_search_chats_after_update after update of chat_title on chats
+	begin
+		delete from chat_search where rowid = -old.chat_id;
+		insert into chat_search (rowid, chat_search_text, chat_id, chat_event_uuid)
+		values (-new.chat_id, new.chat_title, new.chat_id, '');
+	end`,
+	`create trigger chat_search_chats_after_delete after delete on chats
+	begin
+		delete from chat_search where rowid = -old.chat_id;
+	end`,
+	`create trigger chat_search_events_after_delete after delete on chat_events
+	begin
+		delete from chat_search where rowid = old.chat_event_id;
+	end`,
+}
+
+// SetupSearch sets up the full-text search index when SQLite has FTS5, which mattn/go-sqlite3 only
+// includes with the sqlite_fts5 build tag, and reports whether search is available. Without FTS5 the
+// triggers are dropped, so that the index is rebuilt once FTS5 is available again.
+func (r *Repository) SetupSearch(ctx context.Context) (bool, error) {
+	var available bool
+	if err := r.db.QueryRowContext(ctx, `select sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available); err != nil {
+		return false, fmt.Errorf("error checking for FTS5: %w", err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	// NOTE: the triggers of earlier versions indexed every streamed update of a message
+	for _, name := range []string{
+		"chat_search_events_after_insert",
+		"chat_search_events_after_update",
+	} {
+		if _, err := tx.ExecContext(ctx, "drop trigger if exists "+name); err != nil {
+			return false, err
+		}
+	}
+	var setUp bool
+	if err := tx.QueryRowContext(ctx, `
+	select exists (select 1 from sqlite_master where type = 'trigger' and name = 'chat_search_chats_after_insert')
+	`).Scan(&setUp); err != nil {
+		return false, err
+	}
+	switch {
+	case !available:
+		for _, name := range []string{
+			"chat_search_chats_after_insert",
+			"chat_search_chats_after_update",
+			"chat_search_chats_after_delete",
+			"chat_search_events_after_delete",
+		} {
+			if _, err := tx.ExecContext(ctx, "drop trigger if exists "+name); err != nil {
+				return false, err
+			}
+		}
+	case !setUp:
+		var createTableQuery = `
+		create virtual table if not exists chat_search using fts5 (
+			chat_search_text,
+			chat_id unindexed,
+			chat_event_uuid unindexed,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)
+		`
+		if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, `delete from chat_search`); err != nil {
+			return false, err
+		}
+		var indexChatsQuery = `
+		insert into chat_search (rowid, chat_search_text, chat_id, chat_event_uuid)
+		select -chat_id, chat_title, chat_id, ''
+		from chats
+		`
+		if _, err := tx.ExecContext(ctx, indexChatsQuery); err != nil {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, indexChatEventsQuery); err != nil {
+			return false, err
+		}
+		for _, trigger := range searchTriggers {
+			if _, err := tx.ExecContext(ctx, trigger); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	r.search = available
+	return available, nil
+}
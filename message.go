@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/markusylisiurunen/juttele/internal/util"
 )
 
 type MessageType string
@@ -101,11 +102,20 @@ type AssistantMessageToolCall struct {
 	FuncArgs string `json:"func_args"`
 }
 
+type AssistantMessageUsage struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CachedTokens     int64 `json:"cached_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+	ReasoningTokens  int64 `json:"reasoning_tokens"`
+}
+
 type AssistantMessage struct {
 	BaseMessage
 	Thinking  string                     `json:"thinking,omitempty"`
 	Content   string                     `json:"content"`
 	ToolCalls []AssistantMessageToolCall `json:"tool_calls,omitempty"`
+	Usage     *AssistantMessageUsage     `json:"usage,omitempty"`
 }
 
 func NewAssistantMessage(content string) *AssistantMessage {
@@ -136,6 +146,10 @@ func (m *AssistantMessage) AppendToolCall(callID, funcName, funcArgs string) {
 	})
 }
 
+func (m *AssistantMessage) SetUsage(usage AssistantMessageUsage) {
+	m.Usage = &usage
+}
+
 func (m *AssistantMessage) MarshalJSON() ([]byte, error) {
 	type Alias AssistantMessage
 	return json.Marshal((*Alias)(m))
@@ -143,7 +157,8 @@ func (m *AssistantMessage) MarshalJSON() ([]byte, error) {
 
 type UserMessage struct {
 	BaseMessage
-	Content string `json:"content"`
+	Content     string       `json:"content"`
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 func NewUserMessage(content string) *UserMessage {
@@ -191,6 +206,21 @@ func (m *ToolMessage) SetError(code int64, message string) {
 	}
 }
 
+// errorPayload renders the error for the model, keeping the code so that e.g. a timed out call can be
+// told apart from a failed one.
+func (m *ToolMessage) errorPayload() string {
+	type payload struct {
+		Error struct {
+			Code    int64  `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	var v payload
+	v.Error.Code = m.Error.Code
+	v.Error.Message = m.Error.Message
+	return string(util.Must(json.Marshal(v)))
+}
+
 func (m *ToolMessage) MarshalJSON() ([]byte, error) {
 	type Alias ToolMessage
 	return json.Marshal((*Alias)(m))
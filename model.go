@@ -2,7 +2,10 @@ package juttele
 
 import (
 	"context"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/markusylisiurunen/juttele/internal/util"
@@ -14,18 +17,56 @@ type ModelPersonality struct {
 	SystemPrompt string
 }
 
+// ModelPricing holds the model's prices in USD per million tokens.
+type ModelPricing struct {
+	Input       float64
+	CachedInput float64
+	CacheWrite  float64
+	Output      float64
+}
+
+func (p ModelPricing) Cost(usage AssistantMessageUsage) float64 {
+	// NOTE: cache reads and writes are a subset of the input tokens and reasoning tokens of the output tokens
+	uncached := max(0, usage.InputTokens-usage.CachedTokens-usage.CacheWriteTokens)
+	cacheWrite := p.CacheWrite
+	if cacheWrite == 0 {
+		cacheWrite = p.Input
+	}
+	cost := float64(uncached)*p.Input +
+		float64(usage.CachedTokens)*p.CachedInput +
+		float64(usage.CacheWriteTokens)*cacheWrite +
+		float64(usage.OutputTokens)*p.Output
+	return cost / 1_000_000
+}
+
 type ModelInfo struct {
 	ID            string
 	Name          string
 	Personalities []ModelPersonality
+	Pricing       ModelPricing
+	// Modalities lists the input modalities the model supports besides text.
+	Modalities []Modality
+}
+
+func (info ModelInfo) Supports(modality Modality) bool {
+	return modality == ModalityText || slices.Contains(info.Modalities, modality)
 }
 
 type GenerationConfig struct {
-	JSON        bool
-	MaxTokens   int64
-	Temperature *float64
-	Think       bool
-	Tools       *ToolCatalog
+	// ApproveToolCall is asked before calling any tool which requires approval. Without it, or if
+	// it does not answer within ToolApprovalTimeout, such calls are rejected.
+	ApproveToolCall     func(ctx context.Context, name, args string) (bool, error)
+	JSON                bool
+	MaxTokens           int64
+	MaxToolCalls        int
+	MaxToolRounds       int
+	NoToolCalls         bool
+	Temperature         *float64
+	Think               bool
+	ToolApprovalTimeout time.Duration
+	ToolCalls           *ToolCallRegistry
+	Tools               *ToolCatalog
+	ToolConcurrency     int
 }
 
 type Model interface {
@@ -34,9 +75,13 @@ type Model interface {
 }
 
 type model struct {
+	baseURL       string
 	displayName   string
 	maxTokens     int64
+	modalities    []Modality
 	personalities []ModelPersonality
+	pricing       ModelPricing
+	promptCaching bool
 	temperature   float64
 }
 
@@ -53,11 +98,26 @@ func (m *model) getModelInfo(id string) ModelInfo {
 		ID:            id,
 		Name:          m.displayName,
 		Personalities: personalities,
+		Pricing:       m.pricing,
+		Modalities:    slices.Clone(m.modalities),
 	}
 }
 
+func (m *model) getBaseURL(fallback string) string {
+	if m.baseURL != "" {
+		return strings.TrimSuffix(m.baseURL, "/")
+	}
+	return fallback
+}
+
 type modelOption func(*model)
 
+func WithBaseURL(baseURL string) modelOption {
+	return func(m *model) {
+		m.baseURL = baseURL
+	}
+}
+
 func WithDisplayName(displayName string) modelOption {
 	return func(m *model) {
 		m.displayName = displayName
@@ -70,6 +130,33 @@ func WithMaxTokens(maxTokens int64) modelOption {
 	}
 }
 
+// WithModalities declares the input modalities, besides text, which the model supports.
+func WithModalities(modalities ...Modality) modelOption {
+	return func(m *model) {
+		m.modalities = append(m.modalities, modalities...)
+	}
+}
+
+func WithPricing(input, cachedInput, output float64) modelOption {
+	return func(m *model) {
+		m.pricing.Input = input
+		m.pricing.CachedInput = cachedInput
+		m.pricing.Output = output
+	}
+}
+
+func WithCacheWritePricing(cacheWrite float64) modelOption {
+	return func(m *model) {
+		m.pricing.CacheWrite = cacheWrite
+	}
+}
+
+func WithPromptCaching() modelOption {
+	return func(m *model) {
+		m.promptCaching = true
+	}
+}
+
 func WithTemperature(temperature float64) modelOption {
 	return func(m *model) {
 		m.temperature = temperature
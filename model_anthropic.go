@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"math"
 	"net/http"
@@ -61,7 +60,7 @@ func (m *anthropicModel) StreamCompletion(
 	// }
 	copied := make([]Message, len(history))
 	copy(copied, history)
-	return streamWithTools(ctx, opts.Tools, &copied, func() <-chan Result[Message] {
+	return streamWithTools(ctx, opts, &copied, func(opts GenerationConfig) <-chan Result[Message] {
 		out := make(chan Result[Message], 1)
 		defer close(out)
 		resp, err := m.request(ctx, copied, opts)
@@ -96,7 +95,7 @@ func (m *anthropicModel) isThinkingModel() bool {
 // 	}
 // }
 // 	`
-// 	tools.Register(newFuncTool(
+// 	tools.Register(NewFuncTool(
 // 		"think",
 // 		[]byte(strings.TrimSpace(spec)),
 // 		func(ctx context.Context, args string) (string, error) { return "", nil },
@@ -106,25 +105,41 @@ func (m *anthropicModel) isThinkingModel() bool {
 func (m *anthropicModel) request(
 	ctx context.Context, history []Message, opts GenerationConfig,
 ) (*http.Response, error) {
+	type reqBody_cacheControl struct {
+		Type string `json:"type"`
+	}
 	type reqBody_message_thinking struct {
 		Type      string `json:"type"`
 		Thinking  string `json:"thinking"`
 		Signature string `json:"signature"`
 	}
 	type reqBody_message_text struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type         string                `json:"type"`
+		Text         string                `json:"text"`
+		CacheControl *reqBody_cacheControl `json:"cache_control,omitempty"`
+	}
+	type reqBody_message_source struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+	}
+	type reqBody_message_file struct {
+		Type   string                 `json:"type"`
+		Source reqBody_message_source `json:"source"`
 	}
 	type reqBody_message_toolUse struct {
-		Type  string          `json:"type"`
-		ID    string          `json:"id"`
-		Name  string          `json:"name"`
-		Input json.RawMessage `json:"input"`
+		Type         string                `json:"type"`
+		ID           string                `json:"id"`
+		Name         string                `json:"name"`
+		Input        json.RawMessage       `json:"input"`
+		CacheControl *reqBody_cacheControl `json:"cache_control,omitempty"`
 	}
 	type reqBody_message_toolResult struct {
-		Type      string `json:"type"`
-		ToolUseID string `json:"tool_use_id"`
-		Content   string `json:"content"`
+		Type         string                `json:"type"`
+		ToolUseID    string                `json:"tool_use_id"`
+		Content      string                `json:"content"`
+		IsError      bool                  `json:"is_error,omitempty"`
+		CacheControl *reqBody_cacheControl `json:"cache_control,omitempty"`
 	}
 	type reqBody_message struct {
 		Role    string `json:"role"`
@@ -134,15 +149,19 @@ func (m *anthropicModel) request(
 		Type         string `json:"type"`
 		BudgetTokens int64  `json:"budget_tokens"`
 	}
+	type reqBody_toolChoice struct {
+		Type string `json:"type"`
+	}
 	type reqBody struct {
-		MaxTokens   int64             `json:"max_tokens"`
-		Messages    []reqBody_message `json:"messages"`
-		Model       string            `json:"model"`
-		Stream      bool              `json:"stream"`
-		System      *string           `json:"system,omitempty"`
-		Temperature float64           `json:"temperature"`
-		Thinking    *reqBody_thinking `json:"thinking,omitempty"`
-		Tools       []json.RawMessage `json:"tools,omitempty"`
+		MaxTokens   int64                  `json:"max_tokens"`
+		Messages    []reqBody_message      `json:"messages"`
+		Model       string                 `json:"model"`
+		Stream      bool                   `json:"stream"`
+		System      []reqBody_message_text `json:"system,omitempty"`
+		Temperature float64                `json:"temperature"`
+		Thinking    *reqBody_thinking      `json:"thinking,omitempty"`
+		ToolChoice  *reqBody_toolChoice    `json:"tool_choice,omitempty"`
+		Tools       []json.RawMessage      `json:"tools,omitempty"`
 	}
 	b := reqBody{
 		MaxTokens:   m.maxTokens,
@@ -172,6 +191,9 @@ func (m *anthropicModel) request(
 			}
 			b.Tools = append(b.Tools, spec)
 		}
+		if opts.NoToolCalls {
+			b.ToolChoice = &reqBody_toolChoice{Type: "none"}
+		}
 	}
 	thinkingSignatureSeen := false
 	for _, i := range history {
@@ -180,7 +202,10 @@ func (m *anthropicModel) request(
 			loc, _ := time.LoadLocation("Europe/Helsinki")
 			now := time.Now().In(loc).Format("Monday 2006-01-02 15:04:05")
 			systemPrompt := strings.ReplaceAll(i.Content, "{{current_time}}", now)
-			b.System = &systemPrompt
+			b.System = []reqBody_message_text{{
+				Type: "text",
+				Text: systemPrompt,
+			}}
 		case *AssistantMessage:
 			content := []any{}
 			if signature, _ := i.GetTransientMeta("signature"); i.Thinking != "" && signature != "" {
@@ -220,7 +245,8 @@ func (m *anthropicModel) request(
 				content = append(content, reqBody_message_toolResult{
 					Type:      "tool_result",
 					ToolUseID: i.CallID,
-					Content:   fmt.Sprintf("Error: %s", i.Error.Message),
+					Content:   i.errorPayload(),
+					IsError:   true,
 				})
 			} else {
 				content = append(content, reqBody_message_toolResult{
@@ -234,24 +260,74 @@ func (m *anthropicModel) request(
 				Content: content,
 			})
 		case *UserMessage:
+			// NOTE: attachments go before the text, which Anthropic recommends for images
+			content := []any{}
+			for _, a := range i.Attachments {
+				switch a.Modality() {
+				case ModalityImage, ModalityDocument:
+					fileType := "image"
+					if a.Modality() == ModalityDocument {
+						fileType = "document"
+					}
+					content = append(content, reqBody_message_file{
+						Type: fileType,
+						Source: reqBody_message_source{
+							Type:      "base64",
+							MediaType: a.MediaType,
+							Data:      a.base64(),
+						},
+					})
+				case ModalityText:
+					content = append(content, reqBody_message_text{
+						Type: "text",
+						Text: a.text(),
+					})
+				}
+			}
+			content = append(content, reqBody_message_text{
+				Type: "text",
+				Text: i.Content,
+			})
 			if len(b.Messages) > 0 && b.Messages[len(b.Messages)-1].Role == "user" {
 				idx := len(b.Messages) - 1
-				b.Messages[idx].Content = append(b.Messages[idx].Content, reqBody_message_text{
-					Type: "text",
-					Text: i.Content,
-				})
+				b.Messages[idx].Content = append(b.Messages[idx].Content, content...)
 				continue
 			}
-			content := []any{reqBody_message_text{
-				Type: "text",
-				Text: i.Content,
-			}}
 			b.Messages = append(b.Messages, reqBody_message{
 				Role:    "user",
 				Content: content,
 			})
 		}
 	}
+	if m.promptCaching {
+		cacheControl := &reqBody_cacheControl{Type: "ephemeral"}
+		if len(b.System) > 0 {
+			b.System[len(b.System)-1].CacheControl = cacheControl
+		}
+		if len(b.Tools) > 0 {
+			var tool map[string]json.RawMessage
+			if err := json.Unmarshal(b.Tools[len(b.Tools)-1], &tool); err != nil {
+				return nil, err
+			}
+			tool["cache_control"] = util.Must(json.Marshal(cacheControl))
+			b.Tools[len(b.Tools)-1] = util.Must(json.Marshal(tool))
+		}
+		// NOTE: the history up to the latest message stays the same on the next turn
+		if len(b.Messages) > 0 {
+			content := b.Messages[len(b.Messages)-1].Content
+			switch c := content[len(content)-1].(type) {
+			case reqBody_message_text:
+				c.CacheControl = cacheControl
+				content[len(content)-1] = c
+			case reqBody_message_toolUse:
+				c.CacheControl = cacheControl
+				content[len(content)-1] = c
+			case reqBody_message_toolResult:
+				c.CacheControl = cacheControl
+				content[len(content)-1] = c
+			}
+		}
+	}
 	var buf bytes.Buffer
 	encoder := json.NewEncoder(&buf)
 	encoder.SetEscapeHTML(false)
@@ -259,7 +335,7 @@ func (m *anthropicModel) request(
 		return nil, err
 	}
 	req, err := http.NewRequestWithContext(ctx,
-		http.MethodPost, "https://api.anthropic.com/v1/messages", &buf)
+		http.MethodPost, m.getBaseURL("https://api.anthropic.com/v1")+"/messages", &buf)
 	if err != nil {
 		return nil, err
 	}
@@ -278,58 +354,11 @@ func (m *anthropicModel) request(
 		if err := resp.Body.Close(); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+		return nil, newHTTPStatusError(resp, body)
 	}
 	return resp, nil
 }
 
 func (m *anthropicModel) spec(spec []byte) ([]byte, error) {
-	type OpenAIToolSpec struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Parameters  struct {
-			Type       string `json:"type"`
-			Properties map[string]struct {
-				Type        string `json:"type"`
-				Description string `json:"description"`
-			} `json:"properties"`
-			Required             []string `json:"required"`
-			AdditionalProperties bool     `json:"additionalProperties"`
-		} `json:"parameters"`
-	}
-	type AnthropicToolSpec struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		InputSchema struct {
-			Type       string `json:"type"`
-			Properties map[string]struct {
-				Type        string `json:"type"`
-				Description string `json:"description"`
-			} `json:"properties"`
-			Required []string `json:"required"`
-		} `json:"input_schema"`
-	}
-	var openAITool OpenAIToolSpec
-	if err := json.Unmarshal(spec, &openAITool); err != nil {
-		return nil, err
-	}
-	var anthropicTool AnthropicToolSpec
-	anthropicTool.Name = openAITool.Name
-	anthropicTool.Description = openAITool.Description
-	anthropicTool.InputSchema.Type = openAITool.Parameters.Type
-	anthropicTool.InputSchema.Properties = make(map[string]struct {
-		Type        string `json:"type"`
-		Description string `json:"description"`
-	})
-	for k, v := range openAITool.Parameters.Properties {
-		anthropicTool.InputSchema.Properties[k] = struct {
-			Type        string `json:"type"`
-			Description string `json:"description"`
-		}{
-			Type:        v.Type,
-			Description: v.Description,
-		}
-	}
-	anthropicTool.InputSchema.Required = openAITool.Parameters.Required
-	return json.Marshal(anthropicTool)
+	return anthropicToolSpec(spec)
 }
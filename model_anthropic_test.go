@@ -0,0 +1,116 @@
+package juttele
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAnthropicModelPromptCaching(t *testing.T) {
+	tools := NewToolCatalog()
+	tools.Register(googleTestTool{})
+	tools.Register(NewFuncTool("ping", []byte(`{"name":"ping"}`), func(ctx context.Context, args string) (string, error) {
+		return "pong", nil
+	}))
+	withFiles := NewUserMessage("What are these?")
+	withFiles.Attachments = []Attachment{
+		{ID: "1", Name: "photo.png", MediaType: "image/png", Data: []byte("png")},
+		{ID: "2", Name: "paper.pdf", MediaType: "application/pdf", Data: []byte("pdf")},
+	}
+	calling := NewAssistantMessage("Let me check.")
+	calling.ToolCalls = []AssistantMessageToolCall{{CallID: "call_1", FuncName: "get_weather", FuncArgs: `{"city":"Helsinki"}`}}
+	result := NewToolMessage("call_1")
+	weather := `{"weather":"sunny"}`
+	result.Result = &weather
+	tests := []struct {
+		name    string
+		opts    []modelOption
+		history []Message
+		tools   *ToolCatalog
+		// want lists the paths of every content item which should be cached
+		want []string
+	}{
+		{
+			name:    "disabled",
+			history: []Message{NewSystemMessage("You are a test."), NewUserMessage("hi")},
+			tools:   tools,
+		},
+		{
+			name:    "system, last tool and last message",
+			opts:    []modelOption{WithPromptCaching()},
+			history: []Message{NewSystemMessage("You are a test."), NewUserMessage("hi")},
+			tools:   tools,
+			want:    []string{"system.0", "tools.1", "messages.0.content.0"},
+		},
+		{
+			name:    "without system prompt or tools",
+			opts:    []modelOption{WithPromptCaching()},
+			history: []Message{NewUserMessage("hi"), NewAssistantMessage("hello"), NewUserMessage("bye")},
+			want:    []string{"messages.2.content.0"},
+		},
+		{
+			name:    "files are never cached",
+			opts:    []modelOption{WithPromptCaching()},
+			history: []Message{NewSystemMessage("You are a test."), withFiles},
+			want:    []string{"system.0", "messages.0.content.2"},
+		},
+		{
+			name:    "tool result",
+			opts:    []modelOption{WithPromptCaching()},
+			history: []Message{NewUserMessage("What is the weather in Helsinki?"), calling, result},
+			tools:   tools,
+			want:    []string{"tools.1", "messages.2.content.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				w.Header().Set("content-type", "text/event-stream")
+			}))
+			defer srv.Close()
+			m := NewAnthropicModel("key", "claude-test", append(tt.opts, WithBaseURL(srv.URL))...)
+			for range m.StreamCompletion(context.Background(), tt.history, GenerationConfig{Tools: tt.tools}) {
+			}
+			if body == nil {
+				t.Fatal("no request made")
+			}
+			var got []string
+			for _, key := range []string{"system", "tools"} {
+				gjson.GetBytes(body, key).ForEach(func(idx, v gjson.Result) bool {
+					if v.Get("cache_control").Exists() {
+						got = append(got, key+"."+idx.String())
+					}
+					return true
+				})
+			}
+			gjson.GetBytes(body, "messages").ForEach(func(msgIdx, msg gjson.Result) bool {
+				msg.Get("content").ForEach(func(idx, v gjson.Result) bool {
+					path := "messages." + msgIdx.String() + ".content." + idx.String()
+					if !v.Get("cache_control").Exists() {
+						return true
+					}
+					got = append(got, path)
+					if typ := v.Get("type").String(); typ == "image" || typ == "document" {
+						t.Errorf("file block %s is cached", path)
+					}
+					if typ := v.Get("cache_control.type").String(); typ != "ephemeral" {
+						t.Errorf("cache control of %s = %q", path, typ)
+					}
+					return true
+				})
+				return true
+			})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("cached = %v, want %v\nbody: %s", got, tt.want, strings.TrimSpace(string(body)))
+			}
+		})
+	}
+}
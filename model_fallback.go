@@ -0,0 +1,203 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/util"
+)
+
+var _ Model = (*fallbackModel)(nil)
+
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+type fallbackAttempt struct {
+	ModelID   string `json:"model_id"`
+	ModelName string `json:"model_name"`
+	Error     string `json:"error,omitempty"`
+}
+
+type fallbackModel struct {
+	id     string
+	models []Model
+	policy RetryPolicy
+}
+
+// NewFallbackModel wraps the given models so that transient failures before the first token are
+// retried with exponential backoff, after which the next model is tried. The next model is also
+// tried right away when the failure is specific to the provider, e.g. its credentials, while other
+// errors, like invalid requests, are returned as is.
+func NewFallbackModel(primary Model, fallbacks ...Model) *fallbackModel {
+	m := &fallbackModel{
+		models: append([]Model{primary}, fallbacks...),
+		policy: DefaultRetryPolicy,
+	}
+	id := xxhash.New()
+	util.Must(id.WriteString("fallback"))
+	for _, model := range m.models {
+		util.Must(id.WriteString(model.GetModelInfo().ID))
+	}
+	m.id = "fallback_" + strconv.FormatUint(id.Sum64(), 10)
+	return m
+}
+
+func (m *fallbackModel) WithRetryPolicy(policy RetryPolicy) *fallbackModel {
+	m.policy = policy
+	return m
+}
+
+func (m *fallbackModel) GetModelInfo() ModelInfo {
+	info := m.models[0].GetModelInfo()
+	info.ID = m.id
+	// NOTE: any of the models may end up answering, so only the modalities supported by all of them are
+	info.Modalities = slices.DeleteFunc(slices.Clone(info.Modalities), func(modality Modality) bool {
+		for _, model := range m.models[1:] {
+			if !model.GetModelInfo().Supports(modality) {
+				return true
+			}
+		}
+		return false
+	})
+	return info
+}
+
+func (m *fallbackModel) StreamCompletion(
+	ctx context.Context, history []Message, opts GenerationConfig,
+) <-chan Result[Message] {
+	out := make(chan Result[Message])
+	go func() {
+		defer close(out)
+		attempts := []fallbackAttempt{}
+		var lastErr error
+		for _, model := range m.models {
+			info := model.GetModelInfo()
+			for retry := 0; ; retry++ {
+				attempts = append(attempts, fallbackAttempt{ModelID: info.ID, ModelName: info.Name})
+				started, err := m.attempt(ctx, model, history, opts, attempts, out)
+				if started || err == nil {
+					return
+				}
+				lastErr = err
+				attempts[len(attempts)-1].Error = err.Error()
+				logger.Get().Error("model attempt failed",
+					"model_id", info.ID, "retry", retry, "error", err.Error())
+				if ctx.Err() != nil {
+					out <- Err[Message](ctx.Err())
+					return
+				}
+				if !isTransientError(err) {
+					// NOTE: other models would fail the same way on errors caused by the request itself
+					if !isProviderError(err) {
+						out <- Err[Message](err)
+						return
+					}
+					break
+				}
+				if retry >= m.policy.MaxRetries {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					out <- Err[Message](ctx.Err())
+					return
+				case <-time.After(m.delay(retry, err)):
+				}
+			}
+		}
+		out <- Err[Message](lastErr)
+	}()
+	return out
+}
+
+func (m *fallbackModel) spec(spec []byte) ([]byte, error) {
+	var out []byte
+	for idx, model := range m.models {
+		t, ok := model.(toolSpecTranslator)
+		if !ok {
+			continue
+		}
+		v, err := t.spec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if idx == 0 {
+			out = v
+		}
+	}
+	return out, nil
+}
+
+// attempt streams a single completion, buffering events until the first token so that a failed
+// attempt can be retried without the caller seeing anything. It reports whether anything was sent.
+func (m *fallbackModel) attempt(
+	ctx context.Context,
+	model Model,
+	history []Message,
+	opts GenerationConfig,
+	attempts []fallbackAttempt,
+	out chan<- Result[Message],
+) (bool, error) {
+	info := model.GetModelInfo()
+	attemptsJSON := string(util.Must(json.Marshal(attempts)))
+	started := false
+	var pending *Result[Message]
+	in := model.StreamCompletion(ctx, history, opts)
+	for event := range in {
+		if event.Err != nil {
+			if started {
+				out <- event
+			}
+			for range in {
+				// NOTE: drain the channel to prevent blocking
+			}
+			return started, event.Err
+		}
+		if msg, ok := event.Val.(*AssistantMessage); ok {
+			msg.SetPersistedMeta("model_id", info.ID)
+			msg.SetPersistedMeta("model_name", info.Name)
+			msg.SetPersistedMeta("attempts", attemptsJSON)
+			if msg.Usage != nil {
+				cost := info.Pricing.Cost(*msg.Usage)
+				msg.SetPersistedMeta("cost", strconv.FormatFloat(cost, 'f', -1, 64))
+			}
+			if !started && msg.Thinking == "" && msg.Content == "" && len(msg.ToolCalls) == 0 {
+				pending = &event
+				continue
+			}
+		}
+		started = true
+		out <- event
+	}
+	if !started && pending != nil {
+		out <- *pending
+		started = true
+	}
+	return started, nil
+}
+
+func (m *fallbackModel) delay(retry int, err error) time.Duration {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return min(statusErr.RetryAfter, m.policy.MaxDelay)
+	}
+	delay := m.policy.BaseDelay << retry
+	jitter := time.Duration(rand.Int64N(int64(delay)/2 + 1))
+	return min(delay+jitter, m.policy.MaxDelay)
+}
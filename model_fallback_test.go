@@ -0,0 +1,125 @@
+package juttele
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fallbackTestModel fails with its errors in turn, answering once it runs out of them, and records
+// its name in the shared list of calls.
+type fallbackTestModel struct {
+	name   string
+	errs   []error
+	called *[]string
+}
+
+func (m *fallbackTestModel) GetModelInfo() ModelInfo {
+	return ModelInfo{ID: m.name, Name: m.name}
+}
+
+func (m *fallbackTestModel) StreamCompletion(
+	ctx context.Context, history []Message, opts GenerationConfig,
+) <-chan Result[Message] {
+	*m.called = append(*m.called, m.name)
+	out := make(chan Result[Message], 1)
+	defer close(out)
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		out <- Err[Message](err)
+		return out
+	}
+	out <- Ok[Message](NewAssistantMessage("answer from " + m.name))
+	return out
+}
+
+func TestFallbackModelStreamCompletion(t *testing.T) {
+	status := func(code int) error { return &httpStatusError{StatusCode: code} }
+	tests := []struct {
+		name        string
+		primary     []error
+		fallback    []error
+		wantCalls   []string
+		wantAnswer  string
+		wantErrCode int
+	}{
+		{name: "no errors", wantCalls: []string{"primary"}, wantAnswer: "answer from primary"},
+		{
+			name:       "transient error is retried",
+			primary:    []error{status(http.StatusServiceUnavailable)},
+			wantCalls:  []string{"primary", "primary"},
+			wantAnswer: "answer from primary",
+		},
+		{
+			name:       "transient errors fail over after the retries",
+			primary:    []error{status(http.StatusTooManyRequests), status(http.StatusTooManyRequests)},
+			wantCalls:  []string{"primary", "primary", "fallback"},
+			wantAnswer: "answer from fallback",
+		},
+		{
+			name:       "provider error fails over right away",
+			primary:    []error{status(http.StatusUnauthorized)},
+			wantCalls:  []string{"primary", "fallback"},
+			wantAnswer: "answer from fallback",
+		},
+		{
+			name:        "client error is returned right away",
+			primary:     []error{status(http.StatusBadRequest)},
+			wantCalls:   []string{"primary"},
+			wantErrCode: http.StatusBadRequest,
+		},
+		{
+			name:        "client error of the fallback is returned",
+			primary:     []error{status(http.StatusForbidden)},
+			fallback:    []error{status(http.StatusRequestEntityTooLarge)},
+			wantCalls:   []string{"primary", "fallback"},
+			wantErrCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "last error is returned when every model fails",
+			primary:     []error{status(http.StatusNotFound)},
+			fallback:    []error{status(http.StatusBadGateway), status(http.StatusBadGateway)},
+			wantCalls:   []string{"primary", "fallback", "fallback"},
+			wantErrCode: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called []string
+			m := NewFallbackModel(
+				&fallbackTestModel{name: "primary", errs: tt.primary, called: &called},
+				&fallbackTestModel{name: "fallback", errs: tt.fallback, called: &called},
+			).WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+			var (
+				answer string
+				err    error
+			)
+			for i := range m.StreamCompletion(context.Background(), []Message{NewUserMessage("hi")}, GenerationConfig{}) {
+				if i.Err != nil {
+					err = i.Err
+					continue
+				}
+				if msg, ok := i.Val.(*AssistantMessage); ok {
+					answer = msg.Content
+				}
+			}
+			if !slices.Equal(called, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", called, tt.wantCalls)
+			}
+			if answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", answer, tt.wantAnswer)
+			}
+			var statusErr *httpStatusError
+			switch {
+			case tt.wantErrCode == 0 && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErrCode != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantErrCode):
+				t.Errorf("error = %v, want status %d", err, tt.wantErrCode)
+			}
+		})
+	}
+}
@@ -1,189 +1,263 @@
 package juttele
 
-// import (
-// 	"bytes"
-// 	"context"
-// 	"encoding/json"
-// 	"fmt"
-// 	"io"
-// 	"net/http"
-// 	"strconv"
-// 	"strings"
-// 	"time"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-// 	"github.com/cespare/xxhash/v2"
-// 	"github.com/markusylisiurunen/juttele/internal/util"
-// )
+	"github.com/cespare/xxhash/v2"
+	"github.com/markusylisiurunen/juttele/internal/util"
+)
 
-// var _ Model = (*googleModel)(nil)
+var _ Model = (*googleModel)(nil)
 
-// type googleModel struct {
-// 	*model
-// 	id        string
-// 	apiKey    string
-// 	modelName string
-// }
+type googleModel struct {
+	*model
+	id        string
+	apiKey    string
+	modelName string
+}
 
-// func NewGoogleModel(apiKey string, modelName string, opts ...modelOption) *googleModel {
-// 	m := &googleModel{
-// 		model:     &model{displayName: modelName},
-// 		apiKey:    apiKey,
-// 		modelName: modelName,
-// 	}
-// 	for _, opt := range opts {
-// 		opt(m.model)
-// 	}
-// 	id := xxhash.New()
-// 	util.Must(id.WriteString("google"))
-// 	util.Must(id.WriteString(modelName))
-// 	util.Must(id.WriteString(m.displayName))
-// 	m.id = "google_" + strconv.FormatUint(id.Sum64(), 10)
-// 	return m
-// }
+func NewGoogleModel(apiKey string, modelName string, opts ...modelOption) *googleModel {
+	m := &googleModel{
+		model:     &model{displayName: modelName},
+		apiKey:    apiKey,
+		modelName: modelName,
+	}
+	for _, opt := range opts {
+		opt(m.model)
+	}
+	id := xxhash.New()
+	util.Must(id.WriteString("google"))
+	util.Must(id.WriteString(modelName))
+	util.Must(id.WriteString(m.displayName))
+	m.id = "google_" + strconv.FormatUint(id.Sum64(), 10)
+	return m
+}
 
-// func (m *googleModel) GetModelInfo() ModelInfo {
-// 	return m.getModelInfo(m.id)
-// }
+func (m *googleModel) GetModelInfo() ModelInfo {
+	return m.getModelInfo(m.id)
+}
 
-// func (m *googleModel) StreamCompletion(
-// 	ctx context.Context, history []ChatEvent, opts StreamCompletionOpts,
-// ) <-chan Result[ChatEvent] {
-// 	out := make(chan Result[ChatEvent])
-// 	go func() {
-// 		defer close(out)
-// 		resp, err := m.request(ctx, history, opts)
-// 		if err != nil {
-// 			out <- Err[ChatEvent](err)
-// 			return
-// 		}
-// 		events := streamSSE(resp)
-// 		msg := NewAssistantMessageChatEvent("")
-// 		out <- Ok[ChatEvent](msg)
-// 		for event := range events {
-// 			if event.Err != nil {
-// 				out <- Err[ChatEvent](event.Err)
-// 				return
-// 			}
-// 			if event.Val.T1 != "message" {
-// 				continue
-// 			}
-// 			if string(event.Val.T2) == "[DONE]" {
-// 				break
-// 			}
-// 			type respBody struct {
-// 				Candidates []struct {
-// 					Content struct {
-// 						Parts []struct {
-// 							Text string `json:"text"`
-// 						} `json:"parts"`
-// 					} `json:"content"`
-// 				} `json:"candidates"`
-// 			}
-// 			var b respBody
-// 			if err := json.Unmarshal(event.Val.T2, &b); err != nil {
-// 				out <- Err[ChatEvent](err)
-// 				return
-// 			}
-// 			if len(b.Candidates) == 0 {
-// 				continue
-// 			}
-// 			if len(b.Candidates[0].Content.Parts) == 0 {
-// 				continue
-// 			}
-// 			msg.content += b.Candidates[0].Content.Parts[0].Text
-// 			out <- Ok[ChatEvent](msg)
-// 		}
-// 	}()
-// 	return out
-// }
+func (m *googleModel) StreamCompletion(
+	ctx context.Context, history []Message, opts GenerationConfig,
+) <-chan Result[Message] {
+	copied := make([]Message, len(history))
+	copy(copied, history)
+	return streamWithTools(ctx, opts, &copied, func(opts GenerationConfig) <-chan Result[Message] {
+		out := make(chan Result[Message], 1)
+		defer close(out)
+		resp, err := m.request(ctx, copied, opts)
+		if err != nil {
+			out <- Err[Message](err)
+			return out
+		}
+		return streamGoogle(resp)
+	})
+}
 
-// func (m *googleModel) request(
-// 	ctx context.Context, history []ChatEvent, opts StreamCompletionOpts,
-// ) (*http.Response, error) {
-// 	type reqContentPart struct {
-// 		Text string `json:"text"`
-// 	}
-// 	type reqContent struct {
-// 		Role  *string          `json:"role,omitempty"`
-// 		Parts []reqContentPart `json:"parts"`
-// 	}
-// 	type reqGenerationConfig struct {
-// 		MaxOutputTokens int64   `json:"maxOutputTokens,omitempty"`
-// 		Temperature     float64 `json:"temperature,omitempty"`
-// 	}
-// 	type reqBody struct {
-// 		Contents          []reqContent         `json:"contents"`
-// 		GenerationConfig  *reqGenerationConfig `json:"generationConfig,omitempty"`
-// 		SystemInstruction *reqContent          `json:"systemInstruction,omitempty"`
-// 	}
-// 	b := reqBody{
-// 		Contents: []reqContent{},
-// 	}
-// 	b.GenerationConfig = &reqGenerationConfig{
-// 		MaxOutputTokens: m.maxTokens,
-// 		Temperature:     m.temperature,
-// 	}
-// 	// add system instructions if they exist
-// 	if len(opts.SystemPrompt) > 0 {
-// 		loc, _ := time.LoadLocation("Europe/Helsinki")
-// 		now := time.Now().In(loc).Format("Monday 2006-01-02 15:04:05")
-// 		b.SystemInstruction = &reqContent{
-// 			Parts: []reqContentPart{
-// 				{Text: strings.ReplaceAll(opts.SystemPrompt, "{{current_time}}", now)},
-// 			},
-// 		}
-// 	}
-// 	// add the message history
-// 	for _, i := range history {
-// 		switch i := i.(type) {
-// 		case *AssistantMessageChatEvent:
-// 			role := "model"
-// 			content := reqContent{
-// 				Role:  &role,
-// 				Parts: []reqContentPart{{Text: i.content}},
-// 			}
-// 			b.Contents = append(b.Contents, content)
-// 		case *UserMessageChatEvent:
-// 			role := "user"
-// 			content := reqContent{
-// 				Role:  &role,
-// 				Parts: []reqContentPart{{Text: i.content}},
-// 			}
-// 			b.Contents = append(b.Contents, content)
-// 		}
-// 	}
-// 	// make the request
-// 	var buf bytes.Buffer
-// 	encoder := json.NewEncoder(&buf)
-// 	encoder.SetEscapeHTML(false)
-// 	if err := encoder.Encode(b); err != nil {
-// 		return nil, err
-// 	}
-// 	req, err := http.NewRequestWithContext(ctx,
-// 		http.MethodPost, fmt.Sprintf("https://generativelanguage.googleapis.com/v1alpha/models/%s:streamGenerateContent", m.modelName),
-// 		&buf,
-// 	)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	q := req.URL.Query()
-// 	q.Add("alt", "sse")
-// 	q.Add("key", m.apiKey)
-// 	req.URL.RawQuery = q.Encode()
-// 	req.Header.Set("Content-Type", "application/json")
-// 	resp, err := http.DefaultClient.Do(req)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	if resp.StatusCode != http.StatusOK {
-// 		body, err := io.ReadAll(resp.Body)
-// 		if err != nil {
-// 			return nil, err
-// 		}
-// 		if err := resp.Body.Close(); err != nil {
-// 			return nil, err
-// 		}
-// 		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
-// 	}
-// 	return resp, nil
-// }
+func (m *googleModel) request(
+	ctx context.Context, history []Message, opts GenerationConfig,
+) (*http.Response, error) {
+	type reqBody_part_functionCall struct {
+		Name string          `json:"name"`
+		Args json.RawMessage `json:"args"`
+	}
+	type reqBody_part_functionResponse struct {
+		Name     string          `json:"name"`
+		Response json.RawMessage `json:"response"`
+	}
+	type reqBody_part_inlineData struct {
+		MimeType string `json:"mimeType"`
+		Data     string `json:"data"`
+	}
+	type reqBody_part struct {
+		Text             string                         `json:"text,omitempty"`
+		InlineData       *reqBody_part_inlineData       `json:"inlineData,omitempty"`
+		ThoughtSignature string                         `json:"thoughtSignature,omitempty"`
+		FunctionCall     *reqBody_part_functionCall     `json:"functionCall,omitempty"`
+		FunctionResponse *reqBody_part_functionResponse `json:"functionResponse,omitempty"`
+	}
+	type reqBody_content struct {
+		Role  string         `json:"role,omitempty"`
+		Parts []reqBody_part `json:"parts"`
+	}
+	type reqBody_tool struct {
+		FunctionDeclarations []json.RawMessage `json:"functionDeclarations"`
+	}
+	type reqBody_functionCallingConfig struct {
+		Mode string `json:"mode"`
+	}
+	type reqBody_toolConfig struct {
+		FunctionCallingConfig reqBody_functionCallingConfig `json:"functionCallingConfig"`
+	}
+	type reqBody_thinkingConfig struct {
+		IncludeThoughts bool `json:"includeThoughts"`
+	}
+	type reqBody_generationConfig struct {
+		MaxOutputTokens  int64                   `json:"maxOutputTokens,omitempty"`
+		ResponseMimeType string                  `json:"responseMimeType,omitempty"`
+		Temperature      *float64                `json:"temperature,omitempty"`
+		ThinkingConfig   *reqBody_thinkingConfig `json:"thinkingConfig,omitempty"`
+	}
+	type reqBody struct {
+		Contents          []reqBody_content        `json:"contents"`
+		GenerationConfig  reqBody_generationConfig `json:"generationConfig"`
+		SystemInstruction *reqBody_content         `json:"systemInstruction,omitempty"`
+		ToolConfig        *reqBody_toolConfig      `json:"toolConfig,omitempty"`
+		Tools             []reqBody_tool           `json:"tools,omitempty"`
+	}
+	b := reqBody{
+		Contents: []reqBody_content{},
+		GenerationConfig: reqBody_generationConfig{
+			MaxOutputTokens: m.maxTokens,
+		},
+	}
+	// NOTE: without a configured temperature, Gemini's own default is used
+	if m.temperature != 0 {
+		b.GenerationConfig.Temperature = &m.temperature
+	}
+	if opts.MaxTokens > 0 {
+		b.GenerationConfig.MaxOutputTokens = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		b.GenerationConfig.Temperature = opts.Temperature
+	}
+	if opts.Think {
+		b.GenerationConfig.ThinkingConfig = &reqBody_thinkingConfig{IncludeThoughts: true}
+	}
+	if opts.JSON {
+		b.GenerationConfig.ResponseMimeType = "application/json"
+	}
+	if opts.Tools != nil && opts.Tools.Count() > 0 {
+		tool := reqBody_tool{FunctionDeclarations: []json.RawMessage{}}
+		for _, t := range opts.Tools.List() {
+			spec, err := m.spec(t.Spec())
+			if err != nil {
+				return nil, err
+			}
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, spec)
+		}
+		b.Tools = append(b.Tools, tool)
+		if opts.NoToolCalls {
+			b.ToolConfig = &reqBody_toolConfig{
+				FunctionCallingConfig: reqBody_functionCallingConfig{Mode: "NONE"},
+			}
+		}
+	}
+	// NOTE: Gemini identifies function responses by name, so remember which call ID maps to which function
+	funcNames := map[string]string{}
+	appendContent := func(role string, parts ...reqBody_part) {
+		if len(b.Contents) > 0 && b.Contents[len(b.Contents)-1].Role == role {
+			idx := len(b.Contents) - 1
+			b.Contents[idx].Parts = append(b.Contents[idx].Parts, parts...)
+			return
+		}
+		b.Contents = append(b.Contents, reqBody_content{Role: role, Parts: parts})
+	}
+	for _, i := range history {
+		switch i := i.(type) {
+		case *SystemMessage:
+			loc, _ := time.LoadLocation("Europe/Helsinki")
+			now := time.Now().In(loc).Format("Monday 2006-01-02 15:04:05")
+			systemPrompt := strings.ReplaceAll(i.Content, "{{current_time}}", now)
+			b.SystemInstruction = &reqBody_content{
+				Parts: []reqBody_part{{Text: systemPrompt}},
+			}
+		case *AssistantMessage:
+			parts := []reqBody_part{}
+			if i.Content != "" {
+				parts = append(parts, reqBody_part{Text: i.Content})
+			}
+			signature, _ := i.GetTransientMeta("signature")
+			for idx, t := range i.ToolCalls {
+				funcNames[t.CallID] = t.FuncName
+				args := t.FuncArgs
+				if args == "" {
+					args = "{}"
+				}
+				part := reqBody_part{
+					FunctionCall: &reqBody_part_functionCall{
+						Name: t.FuncName,
+						Args: json.RawMessage(args),
+					},
+				}
+				if idx == 0 {
+					part.ThoughtSignature = signature
+				}
+				parts = append(parts, part)
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			appendContent("model", parts...)
+		case *ToolMessage:
+			var response []byte
+			if i.Error != nil {
+				response = []byte(i.errorPayload())
+			} else if json.Valid([]byte(*i.Result)) && strings.HasPrefix(strings.TrimSpace(*i.Result), "{") {
+				response = []byte(*i.Result)
+			} else {
+				response = util.Must(json.Marshal(map[string]any{"result": *i.Result}))
+			}
+			appendContent("user", reqBody_part{
+				FunctionResponse: &reqBody_part_functionResponse{
+					Name:     funcNames[i.CallID],
+					Response: json.RawMessage(response),
+				},
+			})
+		case *UserMessage:
+			parts := []reqBody_part{}
+			for _, a := range i.Attachments {
+				switch a.Modality() {
+				case ModalityImage, ModalityDocument:
+					parts = append(parts, reqBody_part{
+						InlineData: &reqBody_part_inlineData{MimeType: a.MediaType, Data: a.base64()},
+					})
+				case ModalityText:
+					parts = append(parts, reqBody_part{Text: a.text()})
+				}
+			}
+			appendContent("user", append(parts, reqBody_part{Text: i.Content})...)
+		}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(b); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse",
+		m.getBaseURL("https://generativelanguage.googleapis.com/v1beta"), m.modelName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", m.apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := resp.Body.Close(); err != nil {
+			return nil, err
+		}
+		return nil, newHTTPStatusError(resp, body)
+	}
+	return resp, nil
+}
+
+func (m *googleModel) spec(spec []byte) ([]byte, error) {
+	return googleToolSpec(spec)
+}
@@ -0,0 +1,200 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+type googleTestTool struct{}
+
+func (googleTestTool) Name() string { return "get_weather" }
+
+func (googleTestTool) Spec() []byte {
+	return []byte(`{
+		"name": "get_weather",
+		"description": "Get the weather in a city.",
+		"parameters": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}},
+			"required": ["city"]
+		}
+	}`)
+}
+
+func (googleTestTool) Call(ctx context.Context, args string) (string, error) {
+	return `{"weather":"sunny"}`, nil
+}
+
+func TestGoogleModelStreamCompletion(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies [][]byte
+	)
+	responses := [][]string{
+		{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Let me check","thought":true}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Checking the weather."}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"get_weather","args":{"city":"Helsinki"}},"thoughtSignature":"sig-1"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"thoughtsTokenCount":3}}`,
+		},
+		{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"It is sunny."}]},"finishReason":"STOP"}]}`,
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			http.Error(w, "unexpected path "+r.URL.String(), http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("x-goog-api-key") != "key" {
+			http.Error(w, "unexpected API key", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		idx := len(bodies)
+		bodies = append(bodies, body)
+		mu.Unlock()
+		if idx >= len(responses) {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("content-type", "text/event-stream")
+		for _, data := range responses[idx] {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewGoogleModel("key", "gemini-test", WithBaseURL(srv.URL), WithPersonality("Raw", "You are a test."))
+	tools := NewToolCatalog()
+	if err := tools.Register(googleTestTool{}); err != nil {
+		t.Fatal(err)
+	}
+	history := []Message{
+		NewSystemMessage("You are a test."),
+		NewUserMessage("What is the weather in Helsinki?"),
+	}
+	var messages []Message
+	for i := range m.StreamCompletion(context.Background(), history, GenerationConfig{Tools: tools, Think: true}) {
+		if i.Err != nil {
+			t.Fatalf("unexpected error: %v", i.Err)
+		}
+		if len(messages) == 0 || messages[len(messages)-1].GetID() != i.Val.GetID() {
+			messages = append(messages, i.Val)
+		}
+	}
+
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(messages))
+	}
+	first, ok := messages[0].(*AssistantMessage)
+	if !ok {
+		t.Fatalf("first message is %T, want *AssistantMessage", messages[0])
+	}
+	if first.Thinking != "Let me check" {
+		t.Errorf("thinking = %q", first.Thinking)
+	}
+	if first.Content != "Checking the weather." {
+		t.Errorf("content = %q", first.Content)
+	}
+	if len(first.ToolCalls) != 1 || first.ToolCalls[0].FuncName != "get_weather" ||
+		first.ToolCalls[0].FuncArgs != `{"city":"Helsinki"}` {
+		t.Errorf("tool calls = %+v", first.ToolCalls)
+	}
+	if signature, _ := first.GetTransientMeta("signature"); signature != "sig-1" {
+		t.Errorf("signature = %q", signature)
+	}
+	if first.Usage == nil || first.Usage.InputTokens != 10 || first.Usage.OutputTokens != 8 ||
+		first.Usage.ReasoningTokens != 3 {
+		t.Errorf("usage = %+v", first.Usage)
+	}
+	result, ok := messages[1].(*ToolMessage)
+	if !ok || result.Result == nil || *result.Result != `{"weather":"sunny"}` {
+		t.Errorf("tool message = %+v", messages[1])
+	}
+	last, ok := messages[2].(*AssistantMessage)
+	if !ok || last.Content != "It is sunny." {
+		t.Errorf("last message = %+v", messages[2])
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d requests, want 2", len(bodies))
+	}
+	if gjson.GetBytes(bodies[0], "generationConfig.temperature").Exists() {
+		t.Errorf("temperature sent without being configured: %s", bodies[0])
+	}
+	if !gjson.GetBytes(bodies[0], "generationConfig.thinkingConfig.includeThoughts").Bool() {
+		t.Errorf("thoughts not requested: %s", bodies[0])
+	}
+	if got := gjson.GetBytes(bodies[0], "tools.0.functionDeclarations.0.name").String(); got != "get_weather" {
+		t.Errorf("function declaration = %q", got)
+	}
+	// NOTE: the follow-up request must echo the signature with the function call and carry its response
+	model := gjson.GetBytes(bodies[1], "contents.1")
+	if model.Get("role").String() != "model" {
+		t.Fatalf("second content = %s", model.Raw)
+	}
+	var call struct {
+		FunctionCall struct {
+			Name string          `json:"name"`
+			Args json.RawMessage `json:"args"`
+		} `json:"functionCall"`
+		ThoughtSignature string `json:"thoughtSignature"`
+	}
+	if err := json.Unmarshal([]byte(model.Get("parts.1").Raw), &call); err != nil {
+		t.Fatal(err)
+	}
+	if call.FunctionCall.Name != "get_weather" || call.ThoughtSignature != "sig-1" {
+		t.Errorf("function call part = %+v", call)
+	}
+	response := gjson.GetBytes(bodies[1], "contents.2.parts.0.functionResponse")
+	if response.Get("name").String() != "get_weather" || response.Get("response.weather").String() != "sunny" {
+		t.Errorf("function response = %s", response.Raw)
+	}
+}
+
+func TestGoogleModelTemperature(t *testing.T) {
+	temperature := 0.0
+	tests := []struct {
+		name string
+		opts []modelOption
+		gen  GenerationConfig
+		want string
+	}{
+		{name: "not configured", want: ""},
+		{name: "configured", opts: []modelOption{WithTemperature(0.7)}, want: "0.7"},
+		{name: "overridden with zero", opts: []modelOption{WithTemperature(0.7)},
+			gen: GenerationConfig{Temperature: &temperature}, want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				w.Header().Set("content-type", "text/event-stream")
+				fmt.Fprint(w, `data: {"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`+"\n\n")
+			}))
+			defer srv.Close()
+			m := NewGoogleModel("key", "gemini-test", append(tt.opts, WithBaseURL(srv.URL))...)
+			history := []Message{NewUserMessage("hi")}
+			for i := range m.StreamCompletion(context.Background(), history, tt.gen) {
+				if i.Err != nil {
+					t.Fatalf("unexpected error: %v", i.Err)
+				}
+			}
+			got := gjson.GetBytes(body, "generationConfig.temperature").Raw
+			if strings.TrimSpace(got) != tt.want {
+				t.Errorf("temperature = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
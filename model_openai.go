@@ -0,0 +1,368 @@
+package juttele
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/markusylisiurunen/juttele/internal/util"
+)
+
+var _ Model = (*openAICompatibleModel)(nil)
+
+type openAICompatibleModel struct {
+	*model
+	id        string
+	provider  openAIProvider
+	apiKey    string
+	modelName string
+	routing   []string
+}
+
+// openAIProvider describes how a provider deviates from the OpenAI chat completions API.
+type openAIProvider struct {
+	name    string
+	baseURL string
+	// headers are sent with every request in addition to the authorization header
+	headers map[string]string
+	// streamOptions asks for the usage in the last chunk with `stream_options`
+	streamOptions bool
+	// reasoningFormat is sent as Groq's `reasoning_format` if set
+	reasoningFormat string
+	// openRouter enables OpenRouter's provider routing, reasoning and usage accounting fields
+	openRouter bool
+}
+
+// NewOpenAICompatibleModel creates a model for any endpoint implementing the OpenAI chat completions
+// API (e.g. Groq, DeepSeek or a self-hosted llama.cpp, Ollama or vLLM server). The base URL should
+// point to the API root, e.g. "http://localhost:11434/v1", and the API key may be empty.
+func NewOpenAICompatibleModel(
+	baseURL string, apiKey string, modelName string, opts ...modelOption,
+) *openAICompatibleModel {
+	provider := openAIProvider{name: "openai", baseURL: baseURL, streamOptions: true}
+	return newOpenAICompatibleModel(provider, apiKey, modelName, opts...)
+}
+
+func newOpenAICompatibleModel(
+	provider openAIProvider, apiKey string, modelName string, opts ...modelOption,
+) *openAICompatibleModel {
+	m := &openAICompatibleModel{
+		model:     &model{baseURL: provider.baseURL, displayName: modelName},
+		provider:  provider,
+		apiKey:    apiKey,
+		modelName: modelName,
+	}
+	for _, opt := range opts {
+		opt(m.model)
+	}
+	id := xxhash.New()
+	util.Must(id.WriteString(provider.name))
+	util.Must(id.WriteString(modelName))
+	util.Must(id.WriteString(m.displayName))
+	if provider.name == "openai" {
+		// NOTE: the same model name may be served by multiple endpoints
+		util.Must(id.WriteString(m.baseURL))
+	}
+	m.id = provider.name + "_" + strconv.FormatUint(id.Sum64(), 10)
+	return m
+}
+
+func (m *openAICompatibleModel) GetModelInfo() ModelInfo {
+	return m.getModelInfo(m.id)
+}
+
+func (m *openAICompatibleModel) StreamCompletion(
+	ctx context.Context, history []Message, opts GenerationConfig,
+) <-chan Result[Message] {
+	if m.provider.openRouter && opts.Think {
+		if opts.Tools == nil {
+			opts.Tools = NewToolCatalog()
+		} else {
+			opts.Tools = opts.Tools.Copy()
+		}
+		injectThinkTool(opts.Tools)
+	}
+	copied := make([]Message, len(history))
+	copy(copied, history)
+	return streamWithTools(ctx, opts, &copied, func(opts GenerationConfig) <-chan Result[Message] {
+		out := make(chan Result[Message], 1)
+		defer close(out)
+		resp, err := m.request(ctx, copied, opts)
+		if err != nil {
+			out <- Err[Message](err)
+			return out
+		}
+		return streamOpenAI(resp)
+	})
+}
+
+func (m *openAICompatibleModel) request(
+	ctx context.Context, history []Message, opts GenerationConfig,
+) (*http.Response, error) {
+	type reqBody_toolCall_function struct {
+		Name string `json:"name"`
+		Args string `json:"arguments"`
+	}
+	type reqBody_toolCall struct {
+		ID       string                    `json:"id"`
+		Type     string                    `json:"type"`
+		Function reqBody_toolCall_function `json:"function"`
+	}
+	type reqBody_message struct {
+		Role       string             `json:"role"`
+		Content    any                `json:"content"`
+		ToolCalls  []reqBody_toolCall `json:"tool_calls,omitempty"`
+		ToolCallID string             `json:"tool_call_id,omitempty"`
+	}
+	type reqBody_tool struct {
+		Type     string          `json:"type"`
+		Function json.RawMessage `json:"function"`
+	}
+	type reqBody_provider struct {
+		AllowFallbacks bool     `json:"allow_fallbacks"`
+		Order          []string `json:"order"`
+	}
+	type reqBody_reasoning struct {
+		Effort    string `json:"effort,omitzero"`
+		MaxTokens int64  `json:"max_tokens,omitzero"`
+	}
+	type reqBody_responseFormat struct {
+		Type string `json:"type"`
+	}
+	type reqBody_streamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	}
+	type reqBody_usage struct {
+		Include bool `json:"include"`
+	}
+	type reqBody struct {
+		MaxTokens       int64                   `json:"max_tokens,omitempty"`
+		Messages        []reqBody_message       `json:"messages"`
+		Model           string                  `json:"model"`
+		Provider        *reqBody_provider       `json:"provider,omitempty"`
+		Reasoning       *reqBody_reasoning      `json:"reasoning,omitempty"`
+		ReasoningFormat string                  `json:"reasoning_format,omitempty"`
+		ResponseFormat  *reqBody_responseFormat `json:"response_format,omitempty"`
+		Stream          bool                    `json:"stream"`
+		StreamOptions   *reqBody_streamOptions  `json:"stream_options,omitempty"`
+		Temperature     float64                 `json:"temperature"`
+		ToolChoice      string                  `json:"tool_choice,omitempty"`
+		Tools           []reqBody_tool          `json:"tools,omitempty"`
+		Usage           *reqBody_usage          `json:"usage,omitempty"`
+	}
+	b := reqBody{
+		MaxTokens:       m.maxTokens,
+		Messages:        []reqBody_message{},
+		Model:           m.modelName,
+		ReasoningFormat: m.provider.reasoningFormat,
+		Stream:          true,
+		Temperature:     m.temperature,
+	}
+	if m.provider.streamOptions {
+		b.StreamOptions = &reqBody_streamOptions{IncludeUsage: true}
+	}
+	if m.provider.openRouter {
+		b.Reasoning = &reqBody_reasoning{MaxTokens: 1024}
+		if opts.Think {
+			b.Reasoning = &reqBody_reasoning{Effort: "high"}
+		}
+		b.Usage = &reqBody_usage{Include: true}
+		if len(m.routing) > 0 {
+			b.Provider = &reqBody_provider{
+				AllowFallbacks: false,
+				Order:          m.routing,
+			}
+		}
+	}
+	if opts.MaxTokens > 0 {
+		b.MaxTokens = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		b.Temperature = *opts.Temperature
+	}
+	if opts.JSON {
+		b.ResponseFormat = &reqBody_responseFormat{
+			Type: "json_object",
+		}
+	}
+	if opts.Tools != nil && opts.Tools.Count() > 0 {
+		for _, t := range opts.Tools.List() {
+			spec, err := m.spec(t.Spec())
+			if err != nil {
+				return nil, err
+			}
+			b.Tools = append(b.Tools, reqBody_tool{
+				Type:     "function",
+				Function: spec,
+			})
+		}
+		if opts.NoToolCalls {
+			b.ToolChoice = "none"
+		}
+	}
+	for _, i := range history {
+		switch i := i.(type) {
+		case *SystemMessage:
+			loc, _ := time.LoadLocation("Europe/Helsinki")
+			now := time.Now().In(loc).Format("Monday 2006-01-02 15:04:05")
+			systemPrompt := strings.ReplaceAll(i.Content, "{{current_time}}", now)
+			b.Messages = append(b.Messages, reqBody_message{
+				Role:    "system",
+				Content: systemPrompt,
+			})
+		case *AssistantMessage:
+			msg := reqBody_message{
+				Role:    "assistant",
+				Content: i.Content,
+			}
+			for _, t := range i.ToolCalls {
+				msg.ToolCalls = append(msg.ToolCalls, reqBody_toolCall{
+					ID:   t.CallID,
+					Type: "function",
+					Function: reqBody_toolCall_function{
+						Name: t.FuncName,
+						Args: t.FuncArgs,
+					},
+				})
+			}
+			b.Messages = append(b.Messages, msg)
+		case *ToolMessage:
+			msg := reqBody_message{
+				Role:       "tool",
+				ToolCallID: i.CallID,
+			}
+			if i.Error != nil {
+				msg.Content = i.errorPayload()
+			} else {
+				msg.Content = *i.Result
+			}
+			b.Messages = append(b.Messages, msg)
+		case *UserMessage:
+			content := openAIUserContent(i)
+			if len(b.Messages) > 0 && b.Messages[len(b.Messages)-1].Role == "user" {
+				idx := len(b.Messages) - 1
+				b.Messages[idx].Content = mergeOpenAIUserContent(b.Messages[idx].Content, content)
+				continue
+			}
+			b.Messages = append(b.Messages, reqBody_message{
+				Role:    "user",
+				Content: content,
+			})
+		}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(b); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPost, m.getBaseURL("https://api.openai.com/v1")+"/chat/completions", &buf)
+	if err != nil {
+		return nil, err
+	}
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	for key, value := range m.provider.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := resp.Body.Close(); err != nil {
+			return nil, err
+		}
+		return nil, newHTTPStatusError(resp, body)
+	}
+	return resp, nil
+}
+
+func (m *openAICompatibleModel) spec(spec []byte) ([]byte, error) {
+	if m.provider.openRouter && strings.HasPrefix(m.modelName, "google/") {
+		return googleToolSpec(spec)
+	}
+	return openAIToolSpec(spec)
+}
+
+type openAIContentPart struct {
+	Type     string                 `json:"type"`
+	Text     string                 `json:"text,omitempty"`
+	ImageURL *openAIContentImageURL `json:"image_url,omitempty"`
+	File     *openAIContentFile     `json:"file,omitempty"`
+}
+
+type openAIContentImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIContentFile struct {
+	Filename string `json:"filename"`
+	FileData string `json:"file_data"`
+}
+
+// openAIUserContent translates the user message into the content of an OpenAI message. It stays a
+// plain string unless the message has image or document attachments, as not every compatible API
+// supports content parts.
+func openAIUserContent(msg *UserMessage) any {
+	parts := []openAIContentPart{}
+	plain := true
+	for _, a := range msg.Attachments {
+		switch a.Modality() {
+		case ModalityImage:
+			plain = false
+			parts = append(parts, openAIContentPart{
+				Type:     "image_url",
+				ImageURL: &openAIContentImageURL{URL: a.dataURL()},
+			})
+		case ModalityDocument:
+			plain = false
+			parts = append(parts, openAIContentPart{
+				Type: "file",
+				File: &openAIContentFile{Filename: a.Name, FileData: a.dataURL()},
+			})
+		case ModalityText:
+			parts = append(parts, openAIContentPart{Type: "text", Text: a.text()})
+		}
+	}
+	parts = append(parts, openAIContentPart{Type: "text", Text: msg.Content})
+	if plain {
+		texts := make([]string, 0, len(parts))
+		for _, p := range parts {
+			texts = append(texts, p.Text)
+		}
+		return strings.Join(texts, "\n\n")
+	}
+	return parts
+}
+
+// mergeOpenAIUserContent merges the contents of consecutive user messages.
+func mergeOpenAIUserContent(a, b any) any {
+	aText, aOk := a.(string)
+	bText, bOk := b.(string)
+	if aOk && bOk {
+		return aText + "\n\n" + bText
+	}
+	toParts := func(content any) []openAIContentPart {
+		if text, ok := content.(string); ok {
+			return []openAIContentPart{{Type: "text", Text: text}}
+		}
+		parts, _ := content.([]openAIContentPart)
+		return parts
+	}
+	return slices.Concat(toParts(a), toParts(b))
+}
@@ -0,0 +1,119 @@
+package juttele
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestOpenAICompatibleModelProviders(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  func(baseURL string) *openAICompatibleModel
+		checks map[string]string
+		header map[string]string
+	}{
+		{
+			name: "openai",
+			model: func(baseURL string) *openAICompatibleModel {
+				return NewOpenAICompatibleModel(baseURL, "key", "gpt-test")
+			},
+			checks: map[string]string{
+				"stream_options.include_usage": "true",
+				"reasoning_format":             "",
+				"provider":                     "",
+				"usage":                        "",
+			},
+		},
+		{
+			name: "groq",
+			model: func(baseURL string) *openAICompatibleModel {
+				return NewGroqModel("key", "llama-test", WithBaseURL(baseURL))
+			},
+			checks: map[string]string{
+				"stream_options":   "",
+				"reasoning_format": `"parsed"`,
+			},
+		},
+		{
+			name: "deepseek",
+			model: func(baseURL string) *openAICompatibleModel {
+				return NewDeepSeekModel("key", "deepseek-test", WithBaseURL(baseURL))
+			},
+			checks: map[string]string{
+				"stream_options.include_usage": "true",
+				"reasoning_format":             "",
+			},
+		},
+		{
+			name: "openrouter",
+			model: func(baseURL string) *openAICompatibleModel {
+				return NewOpenRouterModel("key", "google/gemini-test", []string{"Google AI Studio"}, WithBaseURL(baseURL))
+			},
+			checks: map[string]string{
+				"stream_options":           "",
+				"usage.include":            "true",
+				"provider.order.0":         `"Google AI Studio"`,
+				"provider.allow_fallbacks": "false",
+				"reasoning.max_tokens":     "1024",
+				"tools.0.function.name":    `"get_weather"`,
+			},
+			header: map[string]string{"X-Title": "Juttele"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				body   []byte
+				header http.Header
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/chat/completions" {
+					http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+					return
+				}
+				body, _ = io.ReadAll(r.Body)
+				header = r.Header
+				w.Header().Set("content-type", "text/event-stream")
+				fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"ok"}}]}`+"\n\n")
+				fmt.Fprint(w, `data: {"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":1}}`+"\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer srv.Close()
+			tools := NewToolCatalog()
+			if err := tools.Register(googleTestTool{}); err != nil {
+				t.Fatal(err)
+			}
+			m := tt.model(srv.URL)
+			var last Message
+			history := []Message{NewUserMessage("hi")}
+			for i := range m.StreamCompletion(context.Background(), history, GenerationConfig{Tools: tools}) {
+				if i.Err != nil {
+					t.Fatalf("unexpected error: %v", i.Err)
+				}
+				last = i.Val
+			}
+			if msg, ok := last.(*AssistantMessage); !ok || msg.Content != "ok" {
+				t.Errorf("last message = %+v", last)
+			}
+			if got := header.Get("Authorization"); got != "Bearer key" {
+				t.Errorf("authorization = %q", got)
+			}
+			for key, want := range tt.header {
+				if got := header.Get(key); got != want {
+					t.Errorf("header %s = %q, want %q", key, got, want)
+				}
+			}
+			for path, want := range tt.checks {
+				if got := gjson.GetBytes(body, path).Raw; got != want {
+					t.Errorf("%s = %s, want %s", path, got, want)
+				}
+			}
+		})
+	}
+}
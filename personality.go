@@ -0,0 +1,47 @@
+package juttele
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+// personalityAvailableFor reports whether the stored personality can be used with the model.
+func personalityAvailableFor(p repo.Personality, modelID string) bool {
+	return len(p.ModelIDs) == 0 || slices.Contains(p.ModelIDs, modelID)
+}
+
+// modelPersonalities returns the personalities of the model, the ones defined in code followed by
+// the ones managed at runtime.
+func (app *App) modelPersonalities(ctx context.Context, info ModelInfo) ([]ModelPersonality, error) {
+	stored, err := app.repo.ListPersonalities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing personalities: %w", err)
+	}
+	personalities := slices.Clone(info.Personalities)
+	for _, i := range stored.Items {
+		if !personalityAvailableFor(i, info.ID) {
+			continue
+		}
+		personalities = append(personalities, ModelPersonality{
+			ID:           i.UUID,
+			Name:         i.Name,
+			SystemPrompt: i.SystemPrompt,
+		})
+	}
+	return personalities, nil
+}
+
+// isCodePersonality reports whether the personality is defined in code, which makes it read-only.
+func (app *App) isCodePersonality(id string) bool {
+	for _, model := range app.models {
+		if slices.ContainsFunc(model.GetModelInfo().Personalities, func(p ModelPersonality) bool {
+			return p.ID == id
+		}) {
+			return true
+		}
+	}
+	return false
+}
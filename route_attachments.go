@@ -0,0 +1,126 @@
+package juttele
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+const maxAttachmentSize = 32 << 20
+
+type attachmentResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+	Size      int64  `json:"size"`
+}
+
+// uploadAttachmentRouteHandler stores the multipart `file` in the data folder. With the `model_id` query
+// parameter, files the model can't take as input are rejected.
+func (app *App) uploadAttachmentRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading file: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading file: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxAttachmentSize {
+		http.Error(w, fmt.Sprintf("file is larger than %d bytes", maxAttachmentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	mediaType := normalizeMediaType(header.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType = normalizeMediaType(http.DetectContentType(data))
+	}
+	modality, ok := attachmentModality(mediaType)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unsupported media type: %q", mediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if modelID := r.URL.Query().Get("model_id"); modelID != "" {
+		idx := slices.IndexFunc(app.models, func(model Model) bool { return model.GetModelInfo().ID == modelID })
+		if idx == -1 {
+			http.Error(w, fmt.Sprintf("model with ID %q not found", modelID), http.StatusBadRequest)
+			return
+		}
+		if info := app.models[idx].GetModelInfo(); !info.Supports(modality) {
+			http.Error(w, fmt.Sprintf("model %q does not support %s input", info.Name, modality), http.StatusBadRequest)
+			return
+		}
+	}
+	v := attachmentResponse{
+		ID:        uuid.Must(uuid.NewV7()).String(),
+		Name:      filepath.Base(header.Filename),
+		MediaType: mediaType,
+		Size:      int64(len(data)),
+	}
+	if err := os.MkdirAll(filepath.Dir(app.attachmentPath(v.ID)), 0o755); err != nil {
+		logger.Get().Error(fmt.Sprintf("error creating attachments folder: %v", err))
+		http.Error(w, fmt.Sprintf("error creating attachments folder: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := os.WriteFile(app.attachmentPath(v.ID), data, 0o644); err != nil {
+		logger.Get().Error(fmt.Sprintf("error writing attachment: %v", err))
+		http.Error(w, fmt.Sprintf("error writing attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := app.repo.CreateAttachment(ctx, repo.CreateAttachmentArgs{
+		UUID:      v.ID,
+		Name:      v.Name,
+		MediaType: v.MediaType,
+		Size:      v.Size,
+	}); err != nil {
+		logger.Get().Error(fmt.Sprintf("error creating attachment: %v", err))
+		if err := os.Remove(app.attachmentPath(v.ID)); err != nil {
+			logger.Get().Error(fmt.Sprintf("error removing attachment: %v", err))
+		}
+		http.Error(w, fmt.Sprintf("error creating attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func (app *App) attachmentRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	v, err := app.repo.GetAttachment(ctx, repo.GetAttachmentArgs{UUID: r.PathValue("id")})
+	if err != nil {
+		http.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	file, err := os.Open(app.attachmentPath(v.UUID))
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error opening attachment: %v", err))
+		http.Error(w, fmt.Sprintf("error opening attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	// NOTE: text is never rendered by the browser, as e.g. an uploaded HTML file could run scripts
+	mediaType := v.MediaType
+	if modality, _ := attachmentModality(mediaType); modality == ModalityText {
+		mediaType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("content-type", mediaType)
+	w.Header().Set("content-disposition", mime.FormatMediaType("inline", map[string]string{"filename": v.Name}))
+	w.Header().Set("x-content-type-options", "nosniff")
+	http.ServeContent(w, r, v.Name, v.CreatedAt, file)
+}
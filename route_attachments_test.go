@@ -0,0 +1,108 @@
+package juttele
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	app := New("token", WithDataFolder(t.TempDir()))
+	if err := app.initDatabase(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.db.Close() })
+	return app
+}
+
+func uploadTestAttachment(t *testing.T, app *App, name, mediaType string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+name+`"`)
+	header.Set("Content-Type", mediaType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/attachments", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	app.uploadAttachmentRouteHandler(w, req)
+	return w
+}
+
+func TestAttachmentRouteHandler(t *testing.T) {
+	app := newTestApp(t)
+	tests := []struct {
+		name            string
+		mediaType       string
+		data            string
+		wantContentType string
+	}{
+		{name: "page.html", mediaType: "text/html", data: "<script>alert(1)</script>",
+			wantContentType: "text/plain; charset=utf-8"},
+		{name: "data.json", mediaType: "application/json", data: `{"a":1}`,
+			wantContentType: "text/plain; charset=utf-8"},
+		{name: "notes.txt", mediaType: "text/plain", data: "notes", wantContentType: "text/plain; charset=utf-8"},
+		{name: "pixel.png", mediaType: "image/png", data: "\x89PNG\r\n\x1a\n", wantContentType: "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := uploadTestAttachment(t, app, tt.name, tt.mediaType, []byte(tt.data))
+			if w.Code != http.StatusOK {
+				t.Fatalf("upload status = %d: %s", w.Code, w.Body)
+			}
+			var v attachmentResponse
+			if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if v.MediaType != tt.mediaType {
+				t.Errorf("stored media type = %q, want %q", v.MediaType, tt.mediaType)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/attachments/"+v.ID, nil)
+			req.SetPathValue("id", v.ID)
+			w = httptest.NewRecorder()
+			app.attachmentRouteHandler(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if got := w.Header().Get("content-type"); got != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Header().Get("x-content-type-options"); got != "nosniff" {
+				t.Errorf("x-content-type-options = %q", got)
+			}
+			if w.Body.String() != tt.data {
+				t.Errorf("body = %q, want %q", w.Body, tt.data)
+			}
+		})
+	}
+}
+
+func TestUploadAttachmentRouteHandlerRemovesFileOnError(t *testing.T) {
+	app := newTestApp(t)
+	app.db.Close()
+	w := uploadTestAttachment(t, app, "notes.txt", "text/plain", []byte("notes"))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	entries, err := os.ReadDir(filepath.Join(app.configDataFolder, "attachments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %d files left behind, want none", len(entries))
+	}
+}
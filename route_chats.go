@@ -0,0 +1,231 @@
+package juttele
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+const (
+	defaultChatsPageSize = 50
+	maxChatsPageSize     = 200
+	// syncSafetyMargin is how far the `since` cursors returned to clients trail the latest change
+	// they include. Timestamps are taken before the writes commit, so a change can become visible
+	// after a later one has already been synced.
+	syncSafetyMargin = 5 * time.Second
+)
+
+// syncCursor returns the `since` cursor for a response whose latest included change happened at
+// `latest`, falling back to `since` from the request when there were no changes.
+func syncCursor(latest, since time.Time) string {
+	if latest.IsZero() {
+		return since.Format(time.RFC3339Nano)
+	}
+	return latest.Add(-syncSafetyMargin).Format(time.RFC3339Nano)
+}
+
+type (
+	chatsResponse_Chat struct {
+		ID         int64  `json:"id"`
+		Ts         string `json:"ts"`
+		UpdatedAt  string `json:"updated_at"`
+		Title      string `json:"title"`
+		Pinned     bool   `json:"pinned"`
+		Archived   bool   `json:"archived"`
+		ForkedFrom *int64 `json:"forked_from,omitempty"`
+	}
+	chatsResponse struct {
+		Chats      []chatsResponse_Chat `json:"chats"`
+		Deleted    []int64              `json:"deleted,omitempty"`
+		NextCursor string               `json:"next_cursor,omitempty"`
+		Since      string               `json:"since"`
+	}
+)
+
+// chatsRouteHandler lists chats a page at a time. The `cursor` query parameter continues from the
+// `next_cursor` of a previous page and the `since` parameter, taken from the `since` of an earlier
+// response, limits the chats to those changed since then, including the IDs of deleted chats.
+func (app *App) chatsRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	args := repo.ListChatsArgs{Limit: defaultChatsPageSize}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil || v <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit: %q", limit), http.StatusBadRequest)
+			return
+		}
+		args.Limit = min(v, maxChatsPageSize)
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		pinned, id, err := parseChatsCursor(cursor)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid cursor: %q", cursor), http.StatusBadRequest)
+			return
+		}
+		args.AfterPinned, args.AfterID = pinned, id
+	}
+	if since := r.URL.Query().Get("since"); since != "" {
+		v, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %q", since), http.StatusBadRequest)
+			return
+		}
+		args.Since = v
+	}
+	// NOTE: the watermark is read before the chats so that the cursor never skips a change
+	watermark, err := app.repo.GetChatsWatermark(ctx)
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error getting chats watermark: %v", err))
+		http.Error(w, fmt.Sprintf("error getting chats watermark: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var v chatsResponse
+	v.Chats = make([]chatsResponse_Chat, 0)
+	v.Since = syncCursor(watermark, args.Since)
+	chats, err := app.repo.ListChats(ctx, args)
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error listing chats: %v", err))
+		http.Error(w, fmt.Sprintf("error listing chats: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, chat := range chats.Items {
+		vv := chatsResponse_Chat{
+			ID:        chat.ID,
+			Ts:        chat.CreatedAt.Format(time.RFC3339),
+			UpdatedAt: chat.UpdatedAt.Format(time.RFC3339Nano),
+			Title:     chat.Title,
+			Pinned:    chat.Pinned,
+			Archived:  chat.Archived,
+		}
+		if chat.ForkedFromChatID != 0 {
+			vv.ForkedFrom = &chat.ForkedFromChatID
+		}
+		v.Chats = append(v.Chats, vv)
+	}
+	if len(chats.Items) == args.Limit {
+		last := chats.Items[len(chats.Items)-1]
+		v.NextCursor = formatChatsCursor(last.Pinned, last.ID)
+	}
+	// NOTE: deletions are only reported on the first page of an incremental sync
+	if !args.Since.IsZero() && args.AfterID == 0 {
+		tombstones, err := app.repo.ListChatTombstones(ctx, repo.ListChatTombstonesArgs{Since: args.Since})
+		if err != nil {
+			logger.Get().Error(fmt.Sprintf("error listing deleted chats: %v", err))
+			http.Error(w, fmt.Sprintf("error listing deleted chats: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for _, i := range tombstones.Items {
+			v.Deleted = append(v.Deleted, i.ID)
+		}
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func formatChatsCursor(pinned bool, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%t:%d", pinned, id)))
+}
+
+func parseChatsCursor(cursor string) (bool, int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, 0, err
+	}
+	var (
+		pinned bool
+		id     int64
+	)
+	if _, err := fmt.Sscanf(string(b), "%t:%d", &pinned, &id); err != nil {
+		return false, 0, err
+	}
+	return pinned, id, nil
+}
+
+type chatBlocksResponse struct {
+	IDs    []string `json:"ids"`
+	Blocks []Block  `json:"blocks"`
+	Since  string   `json:"since"`
+}
+
+// chatBlocksRouteHandler lists the blocks on the chat's active branch. With the `since` query
+// parameter only the blocks changed since then are included, unless the chat has switched to
+// another branch since then, while `ids` always lists every block on the branch in order so that
+// clients can drop blocks which are no longer on it.
+func (app *App) chatBlocksRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chatID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat ID", http.StatusBadRequest)
+		return
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		since, err = time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+	// NOTE: the chat is read before its events so that a concurrent switch is seen on the next sync
+	chat, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: chatID})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error getting chat: %v", err))
+		http.Error(w, fmt.Sprintf("error getting chat: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// NOTE: the blocks of a branch switched to may all predate `since`, so the client needs all of
+	// them unless the switch is already covered by the cursor, which trails it by the safety margin
+	latest := chat.BranchSwitchedAt
+	if !since.IsZero() && latest.After(since.Add(syncSafetyMargin)) {
+		since = time.Time{}
+	}
+	var v chatBlocksResponse
+	v.IDs = make([]string, 0)
+	v.Blocks = make([]Block, 0)
+	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+		ChatID:     chatID,
+		KindPrefix: "block.",
+	})
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error listing chat events: %v", err))
+		http.Error(w, fmt.Sprintf("error listing chat events: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, i := range events.Items {
+		v.IDs = append(v.IDs, i.UUID)
+		if i.CreatedAt.After(latest) {
+			latest = i.CreatedAt
+		}
+		if !since.IsZero() && !i.CreatedAt.After(since) {
+			continue
+		}
+		b, err := parseBlock(i.Content)
+		if err != nil {
+			logger.Get().Error(fmt.Sprintf("error parsing block: %v", err))
+			http.Error(w, fmt.Sprintf("error parsing block: %v", err), http.StatusInternalServerError)
+			return
+		}
+		v.Blocks = append(v.Blocks, b)
+	}
+	v.Since = syncCursor(latest, since)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
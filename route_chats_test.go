@@ -0,0 +1,167 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+type testChatBlocks struct {
+	IDs    []string `json:"ids"`
+	Blocks []struct {
+		ID string `json:"id"`
+	} `json:"blocks"`
+	Since string `json:"since"`
+}
+
+func getChatBlocks(t *testing.T, app *App, chatID int64, since string) testChatBlocks {
+	t.Helper()
+	target := "/chats/" + strconv.FormatInt(chatID, 10) + "/blocks"
+	if since != "" {
+		target += "?since=" + url.QueryEscape(since)
+	}
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.SetPathValue("id", strconv.FormatInt(chatID, 10))
+	w := httptest.NewRecorder()
+	app.chatBlocksRouteHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var v testChatBlocks
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func (v testChatBlocks) blockIDs() []string {
+	ids := make([]string, 0, len(v.Blocks))
+	for _, b := range v.Blocks {
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+func TestChatBlocksRouteHandler(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t)
+	// NOTE: the chat has two answers to its first message, both written long before the sync
+	var (
+		begin  = time.Now().UTC().Add(-time.Hour)
+		events []repo.ChatEvent
+		uuids  = map[string]string{}
+	)
+	add := func(name, parent, kind string, v interface{ GetID() string }) {
+		content, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids[name] = v.GetID()
+		events = append(events, repo.ChatEvent{
+			CreatedAt:  begin.Add(time.Duration(len(events)) * time.Minute),
+			ParentUUID: uuids[parent],
+			UUID:       v.GetID(),
+			Kind:       kind,
+			Content:    content,
+		})
+	}
+	add("u1", "", "message.user", NewUserMessage("hi"))
+	add("ub1", "u1", "block.text", NewTextBlock("user", "hi"))
+	add("u2a", "u1", "message.user", NewUserMessage("first"))
+	add("ub2a", "u2a", "block.text", NewTextBlock("user", "first"))
+	add("u2b", "u1", "message.user", NewUserMessage("second"))
+	add("ub2b", "u2b", "block.text", NewTextBlock("user", "second"))
+	chatID, err := app.repo.CreateChatWithEvents(ctx, repo.CreateChatWithEventsArgs{
+		Title: "Branches", ActiveEventUUID: uuids["u2b"], Events: events,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full := getChatBlocks(t, app, chatID, "")
+	if want := []string{uuids["ub1"], uuids["ub2b"]}; !slices.Equal(full.blockIDs(), want) {
+		t.Fatalf("blocks = %v, want %v", full.blockIDs(), want)
+	}
+	latest := events[len(events)-1].CreatedAt.Add(-syncSafetyMargin).Format(time.RFC3339Nano)
+	if full.Since != latest {
+		t.Errorf("since = %q, want %q", full.Since, latest)
+	}
+	// NOTE: the latest block is within the safety margin of the cursor, so it is repeated
+	incremental := getChatBlocks(t, app, chatID, full.Since)
+	if want := []string{uuids["ub2b"]}; !slices.Equal(incremental.blockIDs(), want) || len(incremental.IDs) != 2 {
+		t.Errorf("incremental sync = %+v, want blocks %v", incremental, want)
+	}
+
+	if _, err := app.rpcSwitchChatBranch(ctx, []byte(`{"chat_id":`+strconv.FormatInt(chatID, 10)+
+		`,"id":"`+uuids["u2a"]+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+	switched := getChatBlocks(t, app, chatID, incremental.Since)
+	want := []string{uuids["ub1"], uuids["ub2a"]}
+	if !slices.Equal(switched.blockIDs(), want) || !slices.Equal(switched.IDs, want) {
+		t.Fatalf("after switching, blocks = %v and ids = %v, want %v", switched.blockIDs(), switched.IDs, want)
+	}
+	// NOTE: the switch is covered by the cursor returned with it
+	if after := getChatBlocks(t, app, chatID, switched.Since); len(after.Blocks) != 0 {
+		t.Errorf("after syncing the switch, blocks = %v", after.blockIDs())
+	}
+}
+
+func TestChatsRouteHandlerSince(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t)
+	list := func(since string) chatsResponse {
+		t.Helper()
+		target := "/chats"
+		if since != "" {
+			target += "?since=" + url.QueryEscape(since)
+		}
+		w := httptest.NewRecorder()
+		app.chatsRouteHandler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+		var v chatsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	first := createTestChat(t, app, "u1", [][4]string{{"", "u1", "message.user", "user"}})
+	createTestChat(t, app, "u2", [][4]string{{"", "u2", "message.user", "user"}})
+	initial := list("")
+	if len(initial.Chats) != 2 {
+		t.Fatalf("got %d chats, want 2", len(initial.Chats))
+	}
+	// NOTE: the cursor trails the latest change, so an incremental sync right away repeats it
+	since, err := time.Parse(time.RFC3339Nano, initial.Since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated, _ := time.Parse(time.RFC3339Nano, initial.Chats[0].UpdatedAt); !since.Before(updated) {
+		t.Errorf("since = %q is not before the latest change at %q", initial.Since, initial.Chats[0].UpdatedAt)
+	}
+	if again := list(initial.Since); len(again.Chats) == 0 {
+		t.Errorf("incremental sync missed the changes within the safety margin")
+	}
+
+	if err := app.repo.DeleteChat(ctx, repo.DeleteChatArgs{ID: first}); err != nil {
+		t.Fatal(err)
+	}
+	deleted := list(initial.Since)
+	if !slices.Equal(deleted.Deleted, []int64{first}) {
+		t.Errorf("deleted = %v, want [%d]", deleted.Deleted, first)
+	}
+	if slices.ContainsFunc(deleted.Chats, func(c chatsResponse_Chat) bool { return c.ID == first }) {
+		t.Errorf("deleted chat %d listed", first)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
 )
 
 type configResponsePersonality struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ReadOnly bool   `json:"read_only"`
 }
 type configResponseModel struct {
 	ID            string                      `json:"id"`
@@ -20,15 +23,23 @@ type configResponse struct {
 }
 
 func (app *App) configRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	var v configResponse
 	v.Models = make([]configResponseModel, 0)
 	for _, model := range app.models {
 		info := model.GetModelInfo()
-		personalities := make([]configResponsePersonality, 0)
-		for _, personality := range info.Personalities {
+		modelPersonalities, err := app.modelPersonalities(ctx, info)
+		if err != nil {
+			logger.Get().Error(fmt.Sprintf("error listing personalities: %v", err))
+			http.Error(w, fmt.Sprintf("error listing personalities: %v", err), http.StatusInternalServerError)
+			return
+		}
+		personalities := make([]configResponsePersonality, 0, len(modelPersonalities))
+		for _, personality := range modelPersonalities {
 			personalities = append(personalities, configResponsePersonality{
-				ID:   personality.ID,
-				Name: personality.Name,
+				ID:       personality.ID,
+				Name:     personality.Name,
+				ReadOnly: app.isCodePersonality(personality.ID),
 			})
 		}
 		v.Models = append(v.Models, configResponseModel{
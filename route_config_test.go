@@ -0,0 +1,56 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+func TestConfigRouteHandler(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t)
+	gemini := NewGoogleModel("key", "gemini-test", WithPersonality("Neutral", "You are neutral."))
+	claude := NewAnthropicModel("key", "claude-test", WithPersonality("Terse", "You are terse."))
+	app.models = append(app.models, gemini, claude)
+	for _, i := range []repo.CreatePersonalityArgs{
+		{UUID: "everywhere", Name: "Pirate", SystemPrompt: "Arr."},
+		{UUID: "gemini-only", Name: "Poet", SystemPrompt: "Rhyme.", ModelIDs: []string{gemini.GetModelInfo().ID}},
+	} {
+		if err := app.repo.CreatePersonality(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	app.configRouteHandler(w, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var v configResponse
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]configResponsePersonality{
+		gemini.GetModelInfo().ID: {
+			{ID: gemini.GetModelInfo().Personalities[0].ID, Name: "Neutral", ReadOnly: true},
+			{ID: "everywhere", Name: "Pirate"},
+			{ID: "gemini-only", Name: "Poet"},
+		},
+		claude.GetModelInfo().ID: {
+			{ID: claude.GetModelInfo().Personalities[0].ID, Name: "Terse", ReadOnly: true},
+			{ID: "everywhere", Name: "Pirate"},
+		},
+	}
+	if len(v.Models) != len(want) {
+		t.Fatalf("got %d models, want %d", len(v.Models), len(want))
+	}
+	for _, model := range v.Models {
+		if !slices.Equal(model.Personalities, want[model.ID]) {
+			t.Errorf("personalities of %s = %+v, want %+v", model.Name, model.Personalities, want[model.ID])
+		}
+	}
+}
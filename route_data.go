@@ -12,10 +12,14 @@ import (
 
 type (
 	dataResponse_Chat struct {
-		ID     int64   `json:"id"`
-		Ts     string  `json:"ts"`
-		Title  string  `json:"title"`
-		Blocks []Block `json:"blocks"`
+		ID         int64   `json:"id"`
+		Ts         string  `json:"ts"`
+		Title      string  `json:"title"`
+		Pinned     bool    `json:"pinned"`
+		Archived   bool    `json:"archived"`
+		ForkedFrom *int64  `json:"forked_from,omitempty"`
+		Generating bool    `json:"generating"`
+		Blocks     []Block `json:"blocks"`
 	}
 	dataResponse struct {
 		Chats []dataResponse_Chat `json:"chats"`
@@ -26,7 +30,7 @@ func (app *App) dataRouteHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var v dataResponse
 	v.Chats = make([]dataResponse_Chat, 0)
-	chats, err := app.repo.ListChats(ctx)
+	chats, err := app.repo.ListChats(ctx, repo.ListChatsArgs{})
 	if err != nil {
 		logger.Get().Error(fmt.Sprintf("error listing chats: %v", err))
 		http.Error(w, fmt.Sprintf("error listing chats: %v", err), http.StatusInternalServerError)
@@ -34,10 +38,16 @@ func (app *App) dataRouteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	for _, chat := range chats.Items {
 		vv := dataResponse_Chat{
-			ID:     chat.ID,
-			Ts:     chat.CreatedAt.Format(time.RFC3339),
-			Title:  chat.Title,
-			Blocks: make([]Block, 0),
+			ID:         chat.ID,
+			Ts:         chat.CreatedAt.Format(time.RFC3339),
+			Title:      chat.Title,
+			Pinned:     chat.Pinned,
+			Archived:   chat.Archived,
+			Generating: app.getGenerationJob(chat.ID) != nil,
+			Blocks:     make([]Block, 0),
+		}
+		if chat.ForkedFromChatID != 0 {
+			vv.ForkedFrom = &chat.ForkedFromChatID
 		}
 		blocks, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
 			ChatID:     chat.ID,
@@ -0,0 +1,40 @@
+package juttele
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+)
+
+// eventsRouteHandler streams the changes to the chats to the client over a websocket as JSON-RPC
+// notifications: "chat_created", "chat_updated" and "chat_deleted" for the chats themselves and "block"
+// for their blocks. The connection is closed if the client falls too far behind, in which case it should
+// resync through the `/chats` endpoints before subscribing again.
+func (app *App) eventsRouteHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error upgrading to websocket: %v", err))
+		http.Error(w, fmt.Sprintf("error upgrading to websocket: %v", err), http.StatusInternalServerError)
+		return
+	}
+	proxy := newWebSocketProxy(conn)
+	defer proxy.close()
+	go proxy.readLoop()
+	events, unsubscribe := app.hub.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-proxy.closeChan:
+			return
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := proxy.write(msg); err != nil {
+				logger.Get().Error(fmt.Sprintf("error writing event: %v", err))
+				return
+			}
+		}
+	}
+}
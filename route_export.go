@@ -0,0 +1,263 @@
+package juttele
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+const (
+	chatExportVersion     = 1
+	maxChatImportBodySize = 32 << 20
+)
+
+type (
+	chatExport_Event struct {
+		Ts       time.Time       `json:"ts"`
+		ParentID string          `json:"parent_id,omitempty"`
+		ID       string          `json:"id"`
+		Kind     string          `json:"kind"`
+		Content  json.RawMessage `json:"content"`
+	}
+	// chatExport is the JSON export format of a chat, covering every branch of it.
+	chatExport struct {
+		Version       int                `json:"version"`
+		Title         string             `json:"title"`
+		CreatedAt     time.Time          `json:"created_at"`
+		ActiveEventID string             `json:"active_event_id,omitempty"`
+		Events        []chatExport_Event `json:"events"`
+	}
+)
+
+func (app *App) chatExportRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chatID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "markdown" {
+		http.Error(w, fmt.Sprintf("invalid format: %q", format), http.StatusBadRequest)
+		return
+	}
+	chat, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: chatID})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error getting chat: %v", err))
+		http.Error(w, fmt.Sprintf("error getting chat: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var body []byte
+	switch format {
+	case "json":
+		events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+			ChatID:      chatID,
+			AllBranches: true,
+		})
+		if err != nil {
+			logger.Get().Error(fmt.Sprintf("error listing chat events: %v", err))
+			http.Error(w, fmt.Sprintf("error listing chat events: %v", err), http.StatusInternalServerError)
+			return
+		}
+		v := chatExport{
+			Version:       chatExportVersion,
+			Title:         chat.Title,
+			CreatedAt:     chat.CreatedAt,
+			ActiveEventID: chat.ActiveEventUUID,
+			Events:        make([]chatExport_Event, 0, len(events.Items)),
+		}
+		for _, i := range events.Items {
+			if !strings.HasPrefix(i.Kind, "message.") && !strings.HasPrefix(i.Kind, "block.") {
+				continue
+			}
+			v.Events = append(v.Events, chatExport_Event{
+				Ts:       i.CreatedAt,
+				ParentID: i.ParentUUID,
+				ID:       i.UUID,
+				Kind:     i.Kind,
+				Content:  i.Content,
+			})
+		}
+		body, err = json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+	case "markdown":
+		events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+			ChatID:     chatID,
+			KindPrefix: "block.",
+		})
+		if err != nil {
+			logger.Get().Error(fmt.Sprintf("error listing chat events: %v", err))
+			http.Error(w, fmt.Sprintf("error listing chat events: %v", err), http.StatusInternalServerError)
+			return
+		}
+		blocks := make([]Block, 0, len(events.Items))
+		for _, i := range events.Items {
+			b, err := parseBlock(i.Content)
+			if err != nil {
+				logger.Get().Error(fmt.Sprintf("error parsing block: %v", err))
+				http.Error(w, fmt.Sprintf("error parsing block: %v", err), http.StatusInternalServerError)
+				return
+			}
+			blocks = append(blocks, b)
+		}
+		body = []byte(renderChatMarkdown(chat.Title, blocks))
+		w.Header().Set("content-type", "text/markdown; charset=utf-8")
+	}
+	ext := map[string]string{"json": "json", "markdown": "md"}[format]
+	w.Header().Set("content-disposition", fmt.Sprintf(`attachment; filename="chat-%d.%s"`, chatID, ext))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logger.Get().Error(fmt.Sprintf("error writing response: %v", err))
+	}
+}
+
+// renderChatMarkdown renders the blocks of a chat as Markdown, with thinking and tool calls in
+// collapsible sections.
+func renderChatMarkdown(title string, blocks []Block) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", title)
+	role := ""
+	for _, b := range blocks {
+		if b, ok := b.(*TextBlock); ok && b.Role == "user" {
+			role = "user"
+			fmt.Fprintf(&sb, "\n## User\n\n%s\n", strings.TrimSpace(b.Content))
+			continue
+		}
+		if b, ok := b.(*AttachmentBlock); ok {
+			fmt.Fprintf(&sb, "\nAttachment: `%s` (%s)\n", b.Name, b.MediaType)
+			continue
+		}
+		if role != "assistant" {
+			role = "assistant"
+			sb.WriteString("\n## Assistant\n")
+		}
+		switch b := b.(type) {
+		case *ThinkingBlock:
+			fmt.Fprintf(&sb, "\n<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n",
+				strings.TrimSpace(b.Content))
+		case *TextBlock:
+			fmt.Fprintf(&sb, "\n%s\n", strings.TrimSpace(b.Content))
+		case *ToolBlock:
+			fmt.Fprintf(&sb, "\n<details>\n<summary>Tool call: <code>%s</code></summary>\n\n", b.Name)
+			fmt.Fprintf(&sb, "Arguments:\n\n%s\n", markdownCodeBlock("json", b.Args))
+			if b.Result != nil {
+				fmt.Fprintf(&sb, "\nResult:\n\n%s\n", markdownCodeBlock("", *b.Result))
+			}
+			if b.Error != nil {
+				fmt.Fprintf(&sb, "\nError (%d): %s\n", b.Error.Code, b.Error.Message)
+			}
+			sb.WriteString("\n</details>\n")
+		case *ErrorBlock:
+			fmt.Fprintf(&sb, "\n> **Error (%d):** %s\n", b.Error.Code, b.Error.Message)
+		case *NoticeBlock:
+			fmt.Fprintf(&sb, "\n> **Note:** %s\n", b.Message)
+		}
+	}
+	return sb.String()
+}
+
+// markdownCodeBlock fences the content with more backticks than it contains in a row.
+func markdownCodeBlock(lang, content string) string {
+	longest, current := 0, 0
+	for _, c := range content {
+		if c == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(content, "\n") + "\n" + fence
+}
+
+func (app *App) chatImportRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var v chatExport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChatImportBodySize)).Decode(&v); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if v.Version != chatExportVersion {
+		http.Error(w, fmt.Sprintf("unsupported export version: %d", v.Version), http.StatusBadRequest)
+		return
+	}
+	events := make([]repo.ChatEvent, 0, len(v.Events))
+	for _, i := range v.Events {
+		var err error
+		switch {
+		case strings.HasPrefix(i.Kind, "message."):
+			_, err = parseMessage(i.Content)
+		case strings.HasPrefix(i.Kind, "block."):
+			_, err = parseBlock(i.Content)
+		default:
+			err = fmt.Errorf("unknown kind %q", i.Kind)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid event %q: %v", i.ID, err), http.StatusBadRequest)
+			return
+		}
+		events = append(events, repo.ChatEvent{
+			CreatedAt:  i.Ts,
+			ParentUUID: i.ParentID,
+			UUID:       i.ID,
+			Kind:       i.Kind,
+			Content:    i.Content,
+		})
+	}
+	events, uuids, err := renewChatEventUUIDs(events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	args := repo.CreateChatWithEventsArgs{
+		CreatedAt:       v.CreatedAt,
+		Title:           v.Title,
+		ActiveEventUUID: uuids[v.ActiveEventID],
+		Events:          events,
+	}
+	if args.Title == "" {
+		args.Title = "Imported chat"
+	}
+	if args.ActiveEventUUID == "" {
+		for _, i := range events {
+			if i.Kind == "message.user" {
+				args.ActiveEventUUID = i.UUID
+			}
+		}
+	}
+	chatID, err := app.repo.CreateChatWithEvents(ctx, args)
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error importing chat: %v", err))
+		http.Error(w, fmt.Sprintf("error importing chat: %v", err), http.StatusInternalServerError)
+		return
+	}
+	app.publishChat(ctx, "chat_created", chatID)
+	type resp struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp{ChatID: chatID}); err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,65 @@
+package juttele
+
+import "testing"
+
+func TestMarkdownCodeBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		content string
+		want    string
+	}{
+		{name: "plain", lang: "json", content: `{"a":1}`, want: "```json\n{\"a\":1}\n```"},
+		{name: "trailing newlines", content: "a\n\n", want: "```\na\n```"},
+		{name: "empty", content: "", want: "```\n\n```"},
+		{name: "short backtick runs", content: "`a` and ``b``", want: "```\n`a` and ``b``\n```"},
+		{name: "fenced code", lang: "md", content: "```go\nx\n```", want: "````md\n```go\nx\n```\n````"},
+		{name: "long backtick run", content: "a`````b", want: "``````\na`````b\n``````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownCodeBlock(tt.lang, tt.content); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderChatMarkdown(t *testing.T) {
+	search := NewToolBlock("search", `{"query":"weather"}`)
+	search.SetResult("It is sunny.")
+	failed := NewToolBlock("fetch", `{}`)
+	failed.SetError(toolErrorCodeTimeout, "the tool call timed out")
+	blocks := []Block{
+		NewTextBlock("user", "  What is the weather?\n"),
+		NewAttachmentBlock(Attachment{ID: "a1", Name: "map.png", MediaType: "image/png"}),
+		NewThinkingBlock("Let me search.", 100),
+		search,
+		failed,
+		NewTextBlock("assistant", "It is sunny."),
+		NewTextBlock("user", "Thanks!"),
+		NewNoticeBlock("stopped", "The generation was stopped."),
+		NewErrorBlock(500, "upstream failed"),
+	}
+	want := "# Weather\n" +
+		"\n## User\n\nWhat is the weather?\n" +
+		"\nAttachment: `map.png` (image/png)\n" +
+		"\n## Assistant\n" +
+		"\n<details>\n<summary>Thinking</summary>\n\nLet me search.\n\n</details>\n" +
+		"\n<details>\n<summary>Tool call: <code>search</code></summary>\n\n" +
+		"Arguments:\n\n```json\n{\"query\":\"weather\"}\n```\n" +
+		"\nResult:\n\n```\nIt is sunny.\n```\n" +
+		"\n</details>\n" +
+		"\n<details>\n<summary>Tool call: <code>fetch</code></summary>\n\n" +
+		"Arguments:\n\n```json\n{}\n```\n" +
+		"\nError (-32001): the tool call timed out\n" +
+		"\n</details>\n" +
+		"\nIt is sunny.\n" +
+		"\n## User\n\nThanks!\n" +
+		"\n## Assistant\n" +
+		"\n> **Note:** The generation was stopped.\n" +
+		"\n> **Error (500):** upstream failed\n"
+	if got := renderChatMarkdown("Weather", blocks); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/markusylisiurunen/juttele/internal/logger"
 	"github.com/markusylisiurunen/juttele/internal/repo"
 	"github.com/tidwall/gjson"
@@ -35,6 +39,34 @@ func (app *App) rpcRouteHandler(w http.ResponseWriter, r *http.Request) {
 		rpcResp, rpcErr = app.rpcRenameChat(ctx, v.Args)
 	case "delete_chat_event":
 		rpcResp, rpcErr = app.rpcDeleteChatEvent(ctx, v.Args)
+	case "list_chat_event_siblings":
+		rpcResp, rpcErr = app.rpcListChatEventSiblings(ctx, v.Args)
+	case "switch_chat_branch":
+		rpcResp, rpcErr = app.rpcSwitchChatBranch(ctx, v.Args)
+	case "fork_chat":
+		rpcResp, rpcErr = app.rpcForkChat(ctx, v.Args)
+	case "pin_chat":
+		rpcResp, rpcErr = app.rpcUpdateChatPinned(ctx, v.Args, true)
+	case "unpin_chat":
+		rpcResp, rpcErr = app.rpcUpdateChatPinned(ctx, v.Args, false)
+	case "archive_chat":
+		rpcResp, rpcErr = app.rpcUpdateChatArchived(ctx, v.Args, true)
+	case "unarchive_chat":
+		rpcResp, rpcErr = app.rpcUpdateChatArchived(ctx, v.Args, false)
+	case "delete_chat":
+		rpcResp, rpcErr = app.rpcDeleteChat(ctx, v.Args)
+	case "get_chat_settings":
+		rpcResp, rpcErr = app.rpcGetChatSettings(ctx, v.Args)
+	case "update_chat_settings":
+		rpcResp, rpcErr = app.rpcUpdateChatSettings(ctx, v.Args)
+	case "list_personalities":
+		rpcResp, rpcErr = app.rpcListPersonalities(ctx, v.Args)
+	case "create_personality":
+		rpcResp, rpcErr = app.rpcCreatePersonality(ctx, v.Args)
+	case "update_personality":
+		rpcResp, rpcErr = app.rpcUpdatePersonality(ctx, v.Args)
+	case "delete_personality":
+		rpcResp, rpcErr = app.rpcDeletePersonality(ctx, v.Args)
 	default:
 		rpcErr = fmt.Errorf("unknown op: %q", v.Op)
 	}
@@ -63,6 +95,7 @@ func (app *App) rpcCreateChat(ctx context.Context, args []byte) ([]byte, error)
 	if err != nil {
 		return nil, fmt.Errorf("error creating chat: %w", err)
 	}
+	app.publishChat(ctx, "chat_created", id)
 	type resp struct {
 		ChatID int64 `json:"chat_id"`
 	}
@@ -70,125 +103,604 @@ func (app *App) rpcCreateChat(ctx context.Context, args []byte) ([]byte, error)
 }
 
 func (app *App) rpcRenameChat(ctx context.Context, args []byte) ([]byte, error) {
-	// 	chatID := gjson.GetBytes(args, "id").Int()
-	// 	if chatID == 0 {
-	// 		return nil, fmt.Errorf("id is required")
-	// 	}
-	// 	modelID := gjson.GetBytes(args, "model_id").String()
-	// 	if modelID == "" {
-	// 		return nil, fmt.Errorf("model_id is required")
-	// 	}
-	// 	var model Model
-	// 	modelFound := false
-	// 	for _, m := range app.models {
-	// 		if m.GetModelInfo().ID == modelID {
-	// 			model = m
-	// 			modelFound = true
-	// 			break
-	// 		}
-	// 	}
-	// 	if !modelFound {
-	// 		return nil, fmt.Errorf("model with ID %q not found", modelID)
-	// 	}
-	// 	var systemPrompt = `
-	// Your task is to come up with a short name for the following conversation between the user and an LLM assistant.
-	// The name should be concise (roughly 3 to 10 words) and descriptive, capturing the main theme or topic of the conversation.
-	// Do not capitalize every word, only the first word and any proper nouns should be capitalized.
-	// The name should be in English and should not contain any special characters or punctuation.
-
-	// Here is the conversation:
-	// {{conversation}}
-
-	// Your response must be exactly one line long, and should only include the name.
-
-	// <example_response>
-	// Name: A conversation about the weather
-	// <example_response>
-	// 	`
-	// 	systemPrompt = strings.TrimSpace(systemPrompt)
-	// 	conversation := []struct {
-	// 		Role    string `json:"role"`
-	// 		Content string `json:"content"`
-	// 	}{}
-	// 	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{ChatID: chatID})
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("error listing chat events: %w", err)
-	// 	}
-	// 	for _, i := range events.Items {
-	// 		if !strings.HasPrefix(i.Kind, "message.") {
-	// 			continue
-	// 		}
-	// 		event, err := parseChatEvent(i.CreatedAt, i.UUID, i.Kind, i.Content)
-	// 		if err != nil {
-	// 			return nil, fmt.Errorf("error parsing chat event: %w", err)
-	// 		}
-	// 		if i.Kind == chatEventMessageAssistant {
-	// 			conversation = append(conversation, struct {
-	// 				Role    string `json:"role"`
-	// 				Content string `json:"content"`
-	// 			}{
-	// 				Role:    "assistant",
-	// 				Content: event.(*AssistantMessageChatEvent).content,
-	// 			})
-	// 		} else if i.Kind == chatEventMessageUser {
-	// 			conversation = append(conversation, struct {
-	// 				Role    string `json:"role"`
-	// 				Content string `json:"content"`
-	// 			}{
-	// 				Role:    "user",
-	// 				Content: event.(*UserMessageChatEvent).content,
-	// 			})
-	// 		}
-	// 	}
-	// 	systemPrompt = strings.ReplaceAll(systemPrompt,
-	// 		"{{conversation}}",
-	// 		string(util.Must(json.MarshalIndent(conversation, "", "  "))),
-	// 	)
-	// 	opts := StreamCompletionOpts{
-	// 		SystemPrompt: systemPrompt,
-	// 	}
-	// 	out := model.StreamCompletion(ctx, []ChatEvent{
-	// 		NewUserMessageChatEvent("Please rename the chat."),
-	// 		NewAssistantMessageChatEvent("Name:"),
-	// 	}, opts)
-	// 	var completion string
-	// 	for i := range out {
-	// 		if i.Err != nil {
-	// 			return nil, fmt.Errorf("error getting completion: %v", i.Err)
-	// 		}
-	// 		switch i := i.Val.(type) {
-	// 		case *AssistantMessageChatEvent:
-	// 			completion = i.content
-	// 			continue
-	// 		}
-	// 	}
-	// 	completion = strings.TrimSpace(completion)
-	// 	if len(completion) <= 3 || len(completion) >= 256 || strings.Contains(completion, "\n") {
-	// 		return nil, fmt.Errorf("error getting completion: %q", completion)
-	// 	}
-	// 	if err := app.repo.UpdateChat(ctx, repo.UpdateChatArgs{
-	// 		ID:    chatID,
-	// 		Title: completion,
-	// 	}); err != nil {
-	// 		return nil, fmt.Errorf("error updating chat: %w", err)
-	// 	}
+	chatID := gjson.GetBytes(args, "id").Int()
+	if chatID == 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+	title := strings.TrimSpace(gjson.GetBytes(args, "title").String())
+	auto := gjson.GetBytes(args, "auto").Bool()
+	if (title == "") == !auto {
+		return nil, fmt.Errorf("exactly one of title or auto is required")
+	}
+	if auto {
+		events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+			ChatID:     chatID,
+			KindPrefix: "message.",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing chat events: %w", err)
+		}
+		var conversation strings.Builder
+		for _, i := range events.Items {
+			message, err := parseMessage(i.Content)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing message: %w", err)
+			}
+			switch message := message.(type) {
+			case *UserMessage:
+				fmt.Fprintf(&conversation, "<user>\n%s\n</user>\n", message.Content)
+			case *AssistantMessage:
+				if message.Content != "" {
+					fmt.Fprintf(&conversation, "<assistant>\n%s\n</assistant>\n", message.Content)
+				}
+			}
+		}
+		if conversation.Len() == 0 {
+			return nil, fmt.Errorf("chat %d has no messages", chatID)
+		}
+		title, err = app.completeChatTitle(ctx, chatRenameSystemPrompt,
+			"<conversation>\n"+conversation.String()+"</conversation>")
+		if err != nil {
+			return nil, fmt.Errorf("error generating title: %w", err)
+		}
+	}
+	if err := app.repo.UpdateChat(ctx, repo.UpdateChatArgs{ID: chatID, Title: title}); err != nil {
+		return nil, fmt.Errorf("error updating chat: %w", err)
+	}
+	app.publishChat(ctx, "chat_updated", chatID)
+	type resp struct {
+		Title string `json:"title"`
+	}
+	return json.Marshal(resp{Title: title})
+}
+
+// rpcDeleteChatEvent deletes an event within a turn. Turns themselves can't be deleted, as the
+// following turns and the sibling navigation hang off their user message and its text block.
+func (app *App) rpcDeleteChatEvent(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "chat_id").Int()
+	id := gjson.GetBytes(args, "id").String()
+	if chatID == 0 || id == "" {
+		return nil, fmt.Errorf("chat_id and id are required")
+	}
+	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+		ChatID:      chatID,
+		AllBranches: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing chat events: %w", err)
+	}
+	found := false
+	for _, i := range events.Items {
+		if i.UUID != id {
+			continue
+		}
+		found = true
+		if i.Kind == "message.user" ||
+			(i.Kind == "block.text" && gjson.GetBytes(i.Content, "role").String() == "user") {
+			return nil, fmt.Errorf("user messages can't be deleted")
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("event %q not found", id)
+	}
+	if err := app.repo.DeleteChatEvent(ctx, repo.DeleteChatEventArgs{
+		ChatID: chatID,
+		UUID:   id,
+	}); err != nil {
+		return nil, fmt.Errorf("error deleting chat event: %w", err)
+	}
 	type resp struct {
 		Ok bool `json:"ok"`
 	}
 	return json.Marshal(resp{Ok: true})
 }
 
-func (app *App) rpcDeleteChatEvent(ctx context.Context, args []byte) ([]byte, error) {
+func (app *App) rpcListChatEventSiblings(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "chat_id").Int()
+	id := gjson.GetBytes(args, "id").String()
+	if chatID == 0 || id == "" {
+		return nil, fmt.Errorf("chat_id and id are required")
+	}
+	userMessage, _, _, err := app.findUserTurn(ctx, chatID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user message: %w", err)
+	}
+	siblings, err := app.repo.ListChatEventSiblings(ctx, repo.ListChatEventSiblingsArgs{
+		ChatID: chatID,
+		UUID:   userMessage.GetID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing chat event siblings: %w", err)
+	}
+	type respItem struct {
+		ID      string `json:"id"`
+		BlockID string `json:"block_id"`
+		Ts      string `json:"ts"`
+		Active  bool   `json:"active"`
+	}
+	type resp struct {
+		Items []respItem `json:"items"`
+	}
+	v := resp{Items: make([]respItem, 0, len(siblings.Items))}
+	for _, i := range siblings.Items {
+		v.Items = append(v.Items, respItem{
+			ID:      i.UUID,
+			BlockID: i.BlockUUID,
+			Ts:      i.CreatedAt.Format(time.RFC3339),
+			Active:  i.Active,
+		})
+	}
+	return json.Marshal(v)
+}
+
+func (app *App) rpcSwitchChatBranch(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "chat_id").Int()
+	id := gjson.GetBytes(args, "id").String()
+	if chatID == 0 || id == "" {
+		return nil, fmt.Errorf("chat_id and id are required")
+	}
+	userMessage, _, _, err := app.findUserTurn(ctx, chatID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user message: %w", err)
+	}
+	// NOTE: switching to a turn continues along its most recent follow-ups
+	leaf, err := app.repo.GetChatBranchLeaf(ctx, repo.GetChatBranchLeafArgs{
+		ChatID: chatID,
+		UUID:   userMessage.GetID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error finding chat branch leaf: %w", err)
+	}
+	if err := app.repo.UpdateChatBranch(ctx, repo.UpdateChatBranchArgs{
+		ID:        chatID,
+		EventUUID: leaf,
+		Switch:    true,
+	}); err != nil {
+		return nil, fmt.Errorf("error updating chat branch: %w", err)
+	}
+	app.publishChat(ctx, "chat_updated", chatID)
+	type resp struct {
+		Ok bool `json:"ok"`
+	}
+	return json.Marshal(resp{Ok: true})
+}
+
+// isUserTurnEvent reports whether the event is the user's side of a turn: the user message itself, its
+// text block or its attachments.
+func isUserTurnEvent(kind string, content []byte) bool {
+	switch kind {
+	case "message.user", "block.attachment":
+		return true
+	case "block.text":
+		return gjson.GetBytes(content, "role").String() == "user"
+	}
+	return false
+}
+
+func (app *App) rpcForkChat(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "chat_id").Int()
+	id := gjson.GetBytes(args, "id").String()
+	if chatID == 0 || id == "" {
+		return nil, fmt.Errorf("chat_id and id are required")
+	}
+	all, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+		ChatID:      chatID,
+		AllBranches: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing chat events: %w", err)
+	}
+	var (
+		turnUUID   string
+		userOnly   bool
+		foundEvent bool
+	)
+	for _, i := range all.Items {
+		if i.UUID != id {
+			continue
+		}
+		foundEvent = true
+		turnUUID = i.ParentUUID
+		if i.Kind == "message.user" {
+			turnUUID = i.UUID
+		}
+		// NOTE: forking at the user's side of a turn leaves out the answer to it
+		userOnly = isUserTurnEvent(i.Kind, i.Content)
+	}
+	if !foundEvent || turnUUID == "" {
+		return nil, fmt.Errorf("event %q not found", id)
+	}
+	// NOTE: the fork is cut at the turn level, as a turn's blocks are upserted in no particular order
+	// relative to its messages, so their timestamps can't tell which of them precede the event
+	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+		ChatID: chatID,
+		Branch: turnUUID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing chat events: %w", err)
+	}
+	copied := make([]repo.ChatEvent, 0, len(events.Items))
+	for _, i := range events.Items {
+		if !strings.HasPrefix(i.Kind, "message.") && !strings.HasPrefix(i.Kind, "block.") {
+			continue
+		}
+		inTurn := i.UUID == turnUUID || i.ParentUUID == turnUUID
+		if inTurn && userOnly && !isUserTurnEvent(i.Kind, i.Content) {
+			continue
+		}
+		copied = append(copied, repo.ChatEvent{
+			CreatedAt:  i.CreatedAt,
+			ParentUUID: i.ParentUUID,
+			UUID:       i.UUID,
+			Kind:       i.Kind,
+			Content:    i.Content,
+		})
+	}
+	copied, uuids, err := renewChatEventUUIDs(copied)
+	if err != nil {
+		return nil, err
+	}
+	forkArgs := repo.CreateChatWithEventsArgs{
+		ForkedFromChatID:    chatID,
+		ForkedFromEventUUID: id,
+		Events:              copied,
+	}
+	forkArgs.ActiveEventUUID = uuids[turnUUID]
+	chat, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: chatID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting chat: %w", err)
+	}
+	forkArgs.Title = chat.Title
+	if title := gjson.GetBytes(args, "title").String(); title != "" {
+		forkArgs.Title = title
+	}
+	forkID, err := app.repo.CreateChatWithEvents(ctx, forkArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error forking chat: %w", err)
+	}
+	settings, err := app.repo.GetChatSettings(ctx, repo.GetChatSettingsArgs{ChatID: chatID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting chat settings: %w", err)
+	}
+	if settings != (repo.ChatSettings{}) {
+		if err := app.repo.UpdateChatSettings(ctx, repo.UpdateChatSettingsArgs{
+			ChatID:   forkID,
+			Settings: settings,
+		}); err != nil {
+			return nil, fmt.Errorf("error copying chat settings: %w", err)
+		}
+	}
+	app.publishChat(ctx, "chat_created", forkID)
+	type resp struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	return json.Marshal(resp{ChatID: forkID})
+}
+
+func (app *App) rpcUpdateChatPinned(ctx context.Context, args []byte, pinned bool) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "id").Int()
+	if chatID == 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+	if err := app.repo.UpdateChatPinned(ctx, repo.UpdateChatPinnedArgs{
+		ID:     chatID,
+		Pinned: pinned,
+	}); err != nil {
+		return nil, fmt.Errorf("error updating chat: %w", err)
+	}
+	app.publishChat(ctx, "chat_updated", chatID)
+	type resp struct {
+		Ok bool `json:"ok"`
+	}
+	return json.Marshal(resp{Ok: true})
+}
+
+func (app *App) rpcUpdateChatArchived(ctx context.Context, args []byte, archived bool) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "id").Int()
+	if chatID == 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+	if err := app.repo.UpdateChatArchived(ctx, repo.UpdateChatArchivedArgs{
+		ID:       chatID,
+		Archived: archived,
+	}); err != nil {
+		return nil, fmt.Errorf("error updating chat: %w", err)
+	}
+	app.publishChat(ctx, "chat_updated", chatID)
+	type resp struct {
+		Ok bool `json:"ok"`
+	}
+	return json.Marshal(resp{Ok: true})
+}
+
+func (app *App) rpcDeleteChat(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "id").Int()
+	if chatID == 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+	if err := app.stopGenerationJob(ctx, chatID); err != nil {
+		return nil, fmt.Errorf("error stopping generation: %w", err)
+	}
+	if err := app.repo.DeleteChat(ctx, repo.DeleteChatArgs{ID: chatID}); err != nil {
+		return nil, fmt.Errorf("error deleting chat: %w", err)
+	}
+	app.publishChatDeleted(chatID)
+	type resp struct {
+		Ok bool `json:"ok"`
+	}
+	return json.Marshal(resp{Ok: true})
+}
+
+type chatSettingsResponse struct {
+	ModelID       *string  `json:"model_id"`
+	PersonalityID *string  `json:"personality_id"`
+	SystemPrompt  *string  `json:"system_prompt"`
+	Temperature   *float64 `json:"temperature"`
+	MaxTokens     *int64   `json:"max_tokens"`
+	Think         *bool    `json:"think"`
+	UseTools      *bool    `json:"use_tools"`
+}
+
+func newChatSettingsResponse(settings repo.ChatSettings) chatSettingsResponse {
+	v := chatSettingsResponse{
+		Temperature: settings.Temperature,
+		Think:       settings.Think,
+		UseTools:    settings.UseTools,
+	}
+	if settings.ModelID != "" {
+		v.ModelID = &settings.ModelID
+	}
+	if settings.PersonalityID != "" {
+		v.PersonalityID = &settings.PersonalityID
+	}
+	if settings.SystemPrompt != "" {
+		v.SystemPrompt = &settings.SystemPrompt
+	}
+	if settings.MaxTokens != 0 {
+		v.MaxTokens = &settings.MaxTokens
+	}
+	return v
+}
+
+func (app *App) rpcGetChatSettings(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "id").Int()
+	if chatID == 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+	if _, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: chatID}); err != nil {
+		return nil, fmt.Errorf("error getting chat: %w", err)
+	}
+	settings, err := app.repo.GetChatSettings(ctx, repo.GetChatSettingsArgs{ChatID: chatID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting chat settings: %w", err)
+	}
+	return json.Marshal(newChatSettingsResponse(settings))
+}
+
+// rpcUpdateChatSettings updates the settings given in the args, leaving the others as they are. A
+// null value clears the setting.
+func (app *App) rpcUpdateChatSettings(ctx context.Context, args []byte) ([]byte, error) {
+	chatID := gjson.GetBytes(args, "id").Int()
+	if chatID == 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+	if _, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: chatID}); err != nil {
+		return nil, fmt.Errorf("error getting chat: %w", err)
+	}
+	settings, err := app.repo.GetChatSettings(ctx, repo.GetChatSettingsArgs{ChatID: chatID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting chat settings: %w", err)
+	}
+	if v := gjson.GetBytes(args, "model_id"); v.Exists() {
+		settings.ModelID = v.String()
+	}
+	if v := gjson.GetBytes(args, "personality_id"); v.Exists() {
+		settings.PersonalityID = v.String()
+	}
+	if v := gjson.GetBytes(args, "system_prompt"); v.Exists() {
+		settings.SystemPrompt = v.String()
+	}
+	if v := gjson.GetBytes(args, "temperature"); v.Exists() {
+		settings.Temperature = nil
+		if v.Type != gjson.Null {
+			temperature := v.Float()
+			settings.Temperature = &temperature
+		}
+	}
+	if v := gjson.GetBytes(args, "max_tokens"); v.Exists() {
+		settings.MaxTokens = v.Int()
+	}
+	if v := gjson.GetBytes(args, "think"); v.Exists() {
+		settings.Think = nil
+		if v.Type != gjson.Null {
+			think := v.Bool()
+			settings.Think = &think
+		}
+	}
+	if v := gjson.GetBytes(args, "use_tools"); v.Exists() {
+		settings.UseTools = nil
+		if v.Type != gjson.Null {
+			useTools := v.Bool()
+			settings.UseTools = &useTools
+		}
+	}
+	if err := app.validateChatSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+	if err := app.repo.UpdateChatSettings(ctx, repo.UpdateChatSettingsArgs{
+		ChatID:   chatID,
+		Settings: settings,
+	}); err != nil {
+		return nil, fmt.Errorf("error updating chat settings: %w", err)
+	}
+	return json.Marshal(newChatSettingsResponse(settings))
+}
+
+func (app *App) validateChatSettings(ctx context.Context, settings repo.ChatSettings) error {
+	if settings.PersonalityID != "" && settings.ModelID == "" {
+		return fmt.Errorf("personality_id requires model_id")
+	}
+	if settings.ModelID != "" {
+		idx := slices.IndexFunc(app.models, func(model Model) bool { return model.GetModelInfo().ID == settings.ModelID })
+		if idx == -1 {
+			return fmt.Errorf("model with ID %q not found", settings.ModelID)
+		}
+		personalities, err := app.modelPersonalities(ctx, app.models[idx].GetModelInfo())
+		if err != nil {
+			return err
+		}
+		if settings.PersonalityID != "" && !slices.ContainsFunc(personalities, func(p ModelPersonality) bool {
+			return p.ID == settings.PersonalityID
+		}) {
+			return fmt.Errorf("personality with ID %q not found", settings.PersonalityID)
+		}
+	}
+	if settings.Temperature != nil && (*settings.Temperature < 0 || *settings.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if settings.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative")
+	}
+	return nil
+}
+
+type personalityResponse struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	SystemPrompt string   `json:"system_prompt"`
+	ModelIDs     []string `json:"model_ids"`
+	ReadOnly     bool     `json:"read_only"`
+}
+
+// rpcListPersonalities lists the personalities defined in code, as read-only entries, followed by
+// the ones managed at runtime.
+func (app *App) rpcListPersonalities(ctx context.Context, args []byte) ([]byte, error) {
+	personalities := make([]personalityResponse, 0)
+	for _, model := range app.models {
+		info := model.GetModelInfo()
+		for _, i := range info.Personalities {
+			personalities = append(personalities, personalityResponse{
+				ID:           i.ID,
+				Name:         i.Name,
+				SystemPrompt: i.SystemPrompt,
+				ModelIDs:     []string{info.ID},
+				ReadOnly:     true,
+			})
+		}
+	}
+	stored, err := app.repo.ListPersonalities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing personalities: %w", err)
+	}
+	for _, i := range stored.Items {
+		personalities = append(personalities, personalityResponse{
+			ID:           i.UUID,
+			Name:         i.Name,
+			SystemPrompt: i.SystemPrompt,
+			ModelIDs:     i.ModelIDs,
+		})
+	}
+	type resp struct {
+		Personalities []personalityResponse `json:"personalities"`
+	}
+	return json.Marshal(resp{Personalities: personalities})
+}
+
+func (app *App) rpcCreatePersonality(ctx context.Context, args []byte) ([]byte, error) {
+	name := strings.TrimSpace(gjson.GetBytes(args, "name").String())
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	systemPrompt := gjson.GetBytes(args, "system_prompt").String()
+	modelIDs, err := app.personalityModelIDs(args)
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.Must(uuid.NewV7()).String()
+	if err := app.repo.CreatePersonality(ctx, repo.CreatePersonalityArgs{
+		UUID:         id,
+		Name:         name,
+		SystemPrompt: systemPrompt,
+		ModelIDs:     modelIDs,
+	}); err != nil {
+		return nil, fmt.Errorf("error creating personality: %w", err)
+	}
+	type resp struct {
+		ID string `json:"id"`
+	}
+	return json.Marshal(resp{ID: id})
+}
+
+// rpcUpdatePersonality updates the fields given in the args, leaving the others as they are.
+func (app *App) rpcUpdatePersonality(ctx context.Context, args []byte) ([]byte, error) {
 	id := gjson.GetBytes(args, "id").String()
 	if id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	err := app.repo.DeleteChatEvent(ctx, repo.DeleteChatEventArgs{ID: id})
+	if app.isCodePersonality(id) {
+		return nil, fmt.Errorf("personality %q is defined in code and cannot be changed", id)
+	}
+	personality, err := app.repo.GetPersonality(ctx, repo.GetPersonalityArgs{UUID: id})
 	if err != nil {
-		return nil, fmt.Errorf("error deleting chat event: %w", err)
+		return nil, fmt.Errorf("error getting personality: %w", err)
+	}
+	if v := gjson.GetBytes(args, "name"); v.Exists() {
+		personality.Name = strings.TrimSpace(v.String())
+		if personality.Name == "" {
+			return nil, fmt.Errorf("name must not be empty")
+		}
+	}
+	if v := gjson.GetBytes(args, "system_prompt"); v.Exists() {
+		personality.SystemPrompt = v.String()
+	}
+	if gjson.GetBytes(args, "model_ids").Exists() {
+		personality.ModelIDs, err = app.personalityModelIDs(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := app.repo.UpdatePersonality(ctx, repo.UpdatePersonalityArgs{
+		UUID:         personality.UUID,
+		Name:         personality.Name,
+		SystemPrompt: personality.SystemPrompt,
+		ModelIDs:     personality.ModelIDs,
+	}); err != nil {
+		return nil, fmt.Errorf("error updating personality: %w", err)
 	}
 	type resp struct {
 		Ok bool `json:"ok"`
 	}
 	return json.Marshal(resp{Ok: true})
 }
+
+func (app *App) rpcDeletePersonality(ctx context.Context, args []byte) ([]byte, error) {
+	id := gjson.GetBytes(args, "id").String()
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if app.isCodePersonality(id) {
+		return nil, fmt.Errorf("personality %q is defined in code and cannot be deleted", id)
+	}
+	if err := app.repo.DeletePersonality(ctx, repo.DeletePersonalityArgs{UUID: id}); err != nil {
+		return nil, fmt.Errorf("error deleting personality: %w", err)
+	}
+	type resp struct {
+		Ok bool `json:"ok"`
+	}
+	return json.Marshal(resp{Ok: true})
+}
+
+// personalityModelIDs reads the models a personality is scoped to, where none means every model.
+func (app *App) personalityModelIDs(args []byte) ([]string, error) {
+	modelIDs := make([]string, 0)
+	for _, i := range gjson.GetBytes(args, "model_ids").Array() {
+		id := i.String()
+		if !slices.ContainsFunc(app.models, func(model Model) bool { return model.GetModelInfo().ID == id }) {
+			return nil, fmt.Errorf("model with ID %q not found", id)
+		}
+		if !slices.Contains(modelIDs, id) {
+			modelIDs = append(modelIDs, id)
+		}
+	}
+	return modelIDs, nil
+}
@@ -0,0 +1,209 @@
+package juttele
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+	"github.com/tidwall/gjson"
+)
+
+// createTestChat creates a chat with the events, given as parent, UUID, kind and role, in the order
+// they were last written. The content of each event records its UUID as the label.
+func createTestChat(t *testing.T, app *App, active string, events [][4]string) int64 {
+	t.Helper()
+	begin := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	items := make([]repo.ChatEvent, 0, len(events))
+	for idx, i := range events {
+		items = append(items, repo.ChatEvent{
+			CreatedAt:  begin.Add(time.Duration(idx) * time.Second),
+			ParentUUID: i[0],
+			UUID:       i[1],
+			Kind:       i[2],
+			Content:    json.RawMessage(fmt.Sprintf(`{"id":%q,"role":%q,"label":%q}`, i[1], i[3], i[1])),
+		})
+	}
+	chatID, err := app.repo.CreateChatWithEvents(context.Background(), repo.CreateChatWithEventsArgs{
+		Title: "Source", ActiveEventUUID: active, Events: items,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chatID
+}
+
+func TestRPCForkChat(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t)
+	// NOTE: the user's blocks are written after their message, and tool blocks after the message
+	// calling the tool was last written
+	chatID := createTestChat(t, app, "u2", [][4]string{
+		{"", "u1", "message.user", "user"},
+		{"u1", "ub1", "block.text", "user"},
+		{"u1", "a1", "message.assistant", ""},
+		{"u1", "ab1", "block.text", "assistant"},
+		{"u1", "tb1", "block.tool", ""},
+		{"u1", "u2", "message.user", "user"},
+		{"u2", "att2", "block.attachment", ""},
+		{"u2", "ub2", "block.text", "user"},
+		{"u2", "a2a", "message.assistant", ""},
+		{"u2", "t2", "message.tool", ""},
+		{"u2", "a2b", "message.assistant", ""},
+		{"u2", "ab2", "block.text", "assistant"},
+		{"u2", "tb2", "block.tool", ""},
+	})
+	turn1 := []string{"u1", "ub1", "a1", "ab1", "tb1"}
+	tests := []struct {
+		name       string
+		id         string
+		want       []string
+		wantActive string
+		wantErr    bool
+	}{
+		{name: "at the first user message", id: "u1", want: []string{"u1", "ub1"}, wantActive: "u1"},
+		{name: "at a later user message", id: "u2", want: append(slices.Clone(turn1), "u2", "att2", "ub2"), wantActive: "u2"},
+		{name: "at a user text block", id: "ub2", want: append(slices.Clone(turn1), "u2", "att2", "ub2"), wantActive: "u2"},
+		{name: "at an assistant message", id: "a1", want: turn1, wantActive: "u1"},
+		{
+			name:       "at an assistant message calling tools",
+			id:         "a2a",
+			want:       append(slices.Clone(turn1), "u2", "att2", "ub2", "a2a", "t2", "a2b", "ab2", "tb2"),
+			wantActive: "u2",
+		},
+		{name: "at an assistant block", id: "ab1", want: turn1, wantActive: "u1"},
+		{name: "unknown event", id: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := app.rpcForkChat(ctx, fmt.Appendf(nil, `{"chat_id":%d,"id":%q}`, chatID, tt.id))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			forkID := gjson.GetBytes(res, "chat_id").Int()
+			chat, err := app.repo.GetChat(ctx, repo.GetChatArgs{ID: forkID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chat.ForkedFromChatID != chatID || chat.Title != "Source" {
+				t.Errorf("chat = %+v", chat)
+			}
+			events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{ChatID: forkID, AllBranches: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(events.Items))
+			uuids := map[string]string{}
+			for _, i := range events.Items {
+				label := gjson.GetBytes(i.Content, "label").String()
+				got = append(got, label)
+				uuids[label] = i.UUID
+				if i.UUID == label {
+					t.Errorf("event %q kept its UUID", label)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			if chat.ActiveEventUUID != uuids[tt.wantActive] {
+				t.Errorf("active event = %q, want the copy of %q", chat.ActiveEventUUID, tt.wantActive)
+			}
+			// NOTE: the active branch of the fork shows the same events
+			active, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{ChatID: forkID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(active.Items) != len(tt.want) {
+				t.Errorf("active branch has %d events, want %d", len(active.Items), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestRPCUpdateChatSettings(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t)
+	gemini := NewGoogleModel("key", "gemini-test", WithPersonality("Neutral", "You are neutral."))
+	claude := NewAnthropicModel("key", "claude-test", WithPersonality("Terse", "You are terse."))
+	app.models = append(app.models, gemini, claude)
+	if err := app.repo.CreatePersonality(ctx, repo.CreatePersonalityArgs{
+		UUID: "gemini-only", Name: "Poet", SystemPrompt: "Rhyme.", ModelIDs: []string{gemini.GetModelInfo().ID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	chatID := createTestChat(t, app, "u1", [][4]string{{"", "u1", "message.user", "user"}})
+	var (
+		geminiID = gemini.GetModelInfo().ID
+		neutral  = gemini.GetModelInfo().Personalities[0].ID
+		terse    = claude.GetModelInfo().Personalities[0].ID
+	)
+	// NOTE: the steps run in order, each updating the settings left by the previous ones
+	tests := []struct {
+		name    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "model and personality",
+			args: fmt.Sprintf(`{"model_id":%q,"personality_id":%q}`, geminiID, neutral),
+			want: fmt.Sprintf(`{"model_id":%q,"personality_id":%q,"system_prompt":null,"temperature":null,"max_tokens":null,"think":null,"use_tools":null}`, geminiID, neutral),
+		},
+		{
+			name: "runtime personality",
+			args: `{"personality_id":"gemini-only","temperature":0,"think":false}`,
+			want: fmt.Sprintf(`{"model_id":%q,"personality_id":"gemini-only","system_prompt":null,"temperature":0,"max_tokens":null,"think":false,"use_tools":null}`, geminiID),
+		},
+		{
+			name: "null clears a setting",
+			args: `{"temperature":null,"max_tokens":2048}`,
+			want: fmt.Sprintf(`{"model_id":%q,"personality_id":"gemini-only","system_prompt":null,"temperature":null,"max_tokens":2048,"think":false,"use_tools":null}`, geminiID),
+		},
+		{name: "unknown model", args: `{"model_id":"missing","personality_id":""}`, wantErr: `model with ID "missing" not found`},
+		{name: "personality of another model", args: fmt.Sprintf(`{"personality_id":%q}`, terse), wantErr: "not found"},
+		{name: "personality without a model", args: `{"model_id":""}`, wantErr: "personality_id requires model_id"},
+		{name: "temperature too high", args: `{"temperature":2.5}`, wantErr: "temperature must be between 0 and 2"},
+		{name: "negative temperature", args: `{"temperature":-0.1}`, wantErr: "temperature must be between 0 and 2"},
+		{name: "negative max tokens", args: `{"max_tokens":-1}`, wantErr: "max_tokens must not be negative"},
+		{
+			name: "clearing the model and personality",
+			args: `{"model_id":"","personality_id":""}`,
+			want: `{"model_id":null,"personality_id":null,"system_prompt":null,"temperature":null,"max_tokens":2048,"think":false,"use_tools":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := fmt.Sprintf(`{"id":%d,%s`, chatID, tt.args[1:])
+			got, err := app.rpcUpdateChatSettings(ctx, []byte(args))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("settings = %s\nwant       %s", got, tt.want)
+			}
+			stored, err := app.rpcGetChatSettings(ctx, []byte(fmt.Sprintf(`{"id":%d}`, chatID)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(stored) != tt.want {
+				t.Errorf("stored settings = %s\nwant              %s", stored, tt.want)
+			}
+		})
+	}
+}
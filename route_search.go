@@ -0,0 +1,84 @@
+package juttele
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+const searchMaxMatches = 100
+
+type (
+	searchResponse_Match struct {
+		EventID string `json:"event_id,omitempty"`
+		Snippet string `json:"snippet"`
+	}
+	searchResponse_Chat struct {
+		ID      int64                  `json:"id"`
+		Ts      string                 `json:"ts"`
+		Title   string                 `json:"title"`
+		Matches []searchResponse_Match `json:"matches"`
+	}
+	searchResponse struct {
+		Chats []searchResponse_Chat `json:"chats"`
+	}
+)
+
+// searchRouteHandler searches chat titles and messages, returning the matching chats best match
+// first. Snippets are HTML with the matched terms wrapped in <mark> elements and matches without an
+// event ID are title matches.
+func (app *App) searchRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	matches, err := app.repo.SearchChats(ctx, repo.SearchChatsArgs{
+		Query: q,
+		Limit: searchMaxMatches,
+	})
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error searching chats: %v", err))
+		http.Error(w, fmt.Sprintf("error searching chats: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var v searchResponse
+	v.Chats = make([]searchResponse_Chat, 0)
+	chatIdx := map[int64]int{}
+	for _, i := range matches.Items {
+		idx, ok := chatIdx[i.ChatID]
+		if !ok {
+			idx = len(v.Chats)
+			chatIdx[i.ChatID] = idx
+			v.Chats = append(v.Chats, searchResponse_Chat{
+				ID:      i.ChatID,
+				Ts:      i.ChatCreatedAt.Format(time.RFC3339),
+				Title:   i.ChatTitle,
+				Matches: make([]searchResponse_Match, 0),
+			})
+		}
+		v.Chats[idx].Matches = append(v.Chats[idx].Matches, searchResponse_Match{
+			EventID: i.EventUUID,
+			Snippet: highlightSnippet(i.Snippet),
+		})
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(
+		repo.SearchChatsHighlightStart, "<mark>",
+		repo.SearchChatsHighlightEnd, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
@@ -3,6 +3,7 @@ package juttele
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"slices"
@@ -18,19 +19,25 @@ import (
 )
 
 type sendRequestTool struct {
-	Name string          `json:"name"`
-	Spec json.RawMessage `json:"spec"`
+	Name      string          `json:"name"`
+	Spec      json.RawMessage `json:"spec"`
+	Serial    bool            `json:"serial"`
+	TimeoutMS int64           `json:"timeout_ms"`
 }
 
 type sendRequest struct {
 	Method string `json:"method"`
 	Params struct {
+		EventID       string            `json:"event_id"`
 		ModelID       string            `json:"model_id"`
 		PersonalityID string            `json:"personality_id"`
 		Content       string            `json:"content"`
+		Attachments   []string          `json:"attachments"`
 		Tools         []sendRequestTool `json:"tools"`
-		UseTools      bool              `json:"use_tools"`
-		Think         bool              `json:"think"`
+		UseTools      *bool             `json:"use_tools"`
+		Think         *bool             `json:"think"`
+		Temperature   *float64          `json:"temperature"`
+		MaxTokens     int64             `json:"max_tokens"`
 	} `json:"params"`
 }
 
@@ -78,12 +85,124 @@ func (app *App) sendRouteHandler(w http.ResponseWriter, r *http.Request) {
 		writeWSError(proxy, "error decoding request", err)
 		return
 	}
-	if v.Method != "generate" {
+	if chatID <= 0 {
+		writeWSError(proxy, "chat ID must be provided", nil)
+		return
+	}
+	if v.Method == "attach" {
+		// NOTE: when nothing is running, closing the connection tells the client that there is nothing to resume
+		job := app.getGenerationJob(chatID)
+		if job == nil {
+			return
+		}
+		if err := app.attachGenerationJob(ctx, job, proxy); err != nil {
+			writeWSError(proxy, "error attaching to generation", err)
+			return
+		}
+		job.serve(proxy)
+		return
+	}
+	var (
+		parentUUID  string
+		userMessage *UserMessage
+		userBlock   *TextBlock
+		isFirst     bool
+		titleChan   chan string
+	)
+	switch v.Method {
+	case "generate":
+		if v.Params.Content == "" {
+			writeWSError(proxy, "content must be provided", nil)
+			return
+		}
+		turns, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
+			ChatID:     chatID,
+			KindPrefix: "message.user",
+		})
+		if err != nil {
+			writeWSError(proxy, "error listing chat events", err)
+			return
+		}
+		// NOTE: new turns continue the active branch
+		if len(turns.Items) > 0 {
+			parentUUID = turns.Items[len(turns.Items)-1].UUID
+		}
+		userMessage = NewUserMessage(v.Params.Content)
+		userBlock = NewTextBlock("user", v.Params.Content)
+		for _, id := range v.Params.Attachments {
+			attachment, err := app.getAttachment(ctx, id)
+			if err != nil {
+				writeWSError(proxy, "error getting attachment", err)
+				return
+			}
+			userMessage.Attachments = append(userMessage.Attachments, attachment)
+		}
+		isFirst = len(turns.Items) == 0
+	case "regenerate", "edit":
+		if v.Params.EventID == "" || (v.Method == "edit" && v.Params.Content == "") {
+			writeWSError(proxy, "event ID and, when editing, content must be provided", nil)
+			return
+		}
+		original, originalParentUUID, originalBlock, err := app.findUserTurn(ctx, chatID, v.Params.EventID)
+		if err != nil {
+			writeWSError(proxy, "error finding user message", err)
+			return
+		}
+		// NOTE: by default, the turn is regenerated with the same model and personality as before
+		if v.Params.ModelID == "" {
+			v.Params.ModelID, _ = original.GetPersistedMeta("model_id")
+		}
+		if v.Params.PersonalityID == "" {
+			v.Params.PersonalityID, _ = original.GetPersistedMeta("personality_id")
+		}
+		content := original.Content
+		if v.Method == "edit" {
+			content = v.Params.Content
+		}
+		// NOTE: the new turn becomes a sibling of the original one, which is kept as an alternative branch
+		parentUUID = originalParentUUID
+		userMessage = NewUserMessage(content)
+		userMessage.Attachments = original.Attachments
+		userBlock = NewTextBlock("user", content)
+		if originalBlock != nil {
+			truncate := jsonrpc.NewNotification("truncate", map[string]any{"block_id": originalBlock.GetID()})
+			if err := proxy.write(truncate); err != nil {
+				writeWSError(proxy, "error writing truncate message", err)
+				return
+			}
+		}
+	default:
 		writeWSError(proxy, "invalid method", nil)
 		return
 	}
-	if chatID <= 0 || v.Params.ModelID == "" || v.Params.PersonalityID == "" || v.Params.Content == "" {
-		writeWSError(proxy, "chat ID, model ID, personality ID, and content must be provided", nil)
+	settings, err := app.repo.GetChatSettings(ctx, repo.GetChatSettingsArgs{ChatID: chatID})
+	if err != nil {
+		writeWSError(proxy, "error getting chat settings", err)
+		return
+	}
+	// NOTE: parameters omitted by the client fall back to the chat's settings
+	if v.Params.ModelID == "" {
+		v.Params.ModelID = settings.ModelID
+	}
+	if v.Params.PersonalityID == "" && v.Params.ModelID == settings.ModelID {
+		v.Params.PersonalityID = settings.PersonalityID
+	}
+	if v.Params.Temperature == nil {
+		v.Params.Temperature = settings.Temperature
+	}
+	if v.Params.MaxTokens == 0 {
+		v.Params.MaxTokens = settings.MaxTokens
+	}
+	if v.Params.UseTools == nil {
+		v.Params.UseTools = settings.UseTools
+	}
+	if v.Params.Think == nil {
+		v.Params.Think = settings.Think
+	}
+	useTools := v.Params.UseTools != nil && *v.Params.UseTools
+	think := v.Params.Think != nil && *v.Params.Think
+	if v.Params.ModelID == "" {
+		writeWSError(proxy, "model ID must be provided", nil)
 		return
 	}
 	modelIdx := slices.IndexFunc(app.models, func(model Model) bool { return model.GetModelInfo().ID == v.Params.ModelID })
@@ -93,34 +212,95 @@ func (app *App) sendRouteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	model := app.models[modelIdx]
 	var systemPrompt *string
-	for _, i := range model.GetModelInfo().Personalities {
-		if i.ID == v.Params.PersonalityID {
-			v := i.SystemPrompt
-			systemPrompt = &v
-			break
+	if v.Params.PersonalityID != "" {
+		personalities, err := app.modelPersonalities(ctx, model.GetModelInfo())
+		if err != nil {
+			writeWSError(proxy, "error listing personalities", err)
+			return
 		}
+		for _, i := range personalities {
+			if i.ID == v.Params.PersonalityID {
+				v := i.SystemPrompt
+				systemPrompt = &v
+				break
+			}
+		}
+		if systemPrompt == nil {
+			writeWSError(proxy, fmt.Sprintf("personality with ID %q not found", v.Params.PersonalityID), nil)
+			return
+		}
+	}
+	// NOTE: the chat's own system prompt overrides the one of the personality
+	if settings.SystemPrompt != "" {
+		systemPrompt = &settings.SystemPrompt
 	}
 	if systemPrompt == nil {
-		writeWSError(proxy, fmt.Sprintf("personality with ID %q not found", v.Params.PersonalityID), nil)
+		writeWSError(proxy, "personality ID must be provided", nil)
 		return
 	}
-	isFirst, err := app.isFirstUserMessage(ctx, chatID)
-	if err != nil {
-		logger.Get().Error(fmt.Sprintf("error checking if first message: %v", err))
-		isFirst = false
+	for _, i := range userMessage.Attachments {
+		if info := model.GetModelInfo(); !info.Supports(i.Modality()) {
+			writeWSError(proxy, fmt.Sprintf("model %q does not support %s input", info.Name, i.Modality()), nil)
+			return
+		}
+	}
+	// NOTE: the generation outlives the connection, so that clients can re-attach to it after a disconnect
+	jobCtx := context.WithoutCancel(ctx)
+	genCtx, stop := context.WithCancelCause(jobCtx)
+	job := newGenerationJob(chatID, userMessage.GetID(), proxy, stop)
+	started := false
+	defer func() {
+		if !started {
+			stop(nil)
+			app.unregisterGenerationJob(job)
+			close(job.done)
+		}
+	}()
+	if useTools {
+		job.toolCalls = NewToolCallRegistry()
+	}
+	if !app.registerGenerationJob(job) {
+		writeWSError(proxy, "a generation is already running for the chat", nil)
+		return
 	}
-	var titleChan chan string
 	if isFirst {
-		titleChan = app.generateChatTitle(ctx, v.Params.Content)
+		titleChan = app.generateChatTitle(jobCtx, v.Params.Content)
 	}
-	if err := app.upsertMessage(ctx, chatID, NewUserMessage(v.Params.Content)); err != nil {
+	userMessage.SetPersistedMeta("model_id", v.Params.ModelID)
+	userMessage.SetPersistedMeta("personality_id", v.Params.PersonalityID)
+	if err := app.upsertMessage(ctx, chatID, parentUUID, userMessage); err != nil {
 		writeWSError(proxy, "error upserting user message", err)
 		return
 	}
-	if err := app.upsertBlock(ctx, chatID, NewTextBlock("user", v.Params.Content)); err != nil {
+	app.indexMessage(ctx, chatID, userMessage.GetID())
+	if err := app.upsertBlock(ctx, chatID, userMessage.GetID(), userBlock); err != nil {
 		writeWSError(proxy, "error upserting user block", err)
 		return
 	}
+	if err := app.repo.UpdateChatBranch(ctx, repo.UpdateChatBranchArgs{
+		ID:        chatID,
+		EventUUID: userMessage.GetID(),
+	}); err != nil {
+		writeWSError(proxy, "error updating chat branch", err)
+		return
+	}
+	if v.Method != "generate" {
+		if err := proxy.write(jsonrpc.NewNotification("block", userBlock)); err != nil {
+			writeWSError(proxy, "error writing block message", err)
+			return
+		}
+	}
+	for _, i := range userMessage.Attachments {
+		block := NewAttachmentBlock(i)
+		if err := app.upsertBlock(ctx, chatID, userMessage.GetID(), block); err != nil {
+			writeWSError(proxy, "error upserting attachment block", err)
+			return
+		}
+		if err := proxy.write(jsonrpc.NewNotification("block", block)); err != nil {
+			writeWSError(proxy, "error writing block message", err)
+			return
+		}
+	}
 	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
 		ChatID:     chatID,
 		KindPrefix: "message.",
@@ -139,57 +319,141 @@ func (app *App) sendRouteHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		history = append(history, message)
 	}
+	if err := app.loadAttachments(history, model.GetModelInfo()); err != nil {
+		writeWSError(proxy, "error loading attachments", err)
+		return
+	}
 	// history = append(history, NewUserMessage(v.Content))
 	opts := GenerationConfig{
-		Tools: NewToolCatalog(),
-		Think: v.Params.Think,
+		MaxTokens:   v.Params.MaxTokens,
+		Temperature: v.Params.Temperature,
+		Tools:       NewToolCatalog(),
+		Think:       think,
 	}
-	if v.Params.UseTools {
-		for _, j := range app.tools {
-			opts.Tools.Register(j)
+	if useTools {
+		opts.ToolCalls = job.toolCalls
+		opts.ApproveToolCall = func(ctx context.Context, name, args string) (bool, error) {
+			return requestToolApproval(ctx, job, name, args)
 		}
+		opts.Tools = app.toolCatalog.Copy()
 		for _, j := range v.Params.Tools {
-			opts.Tools.Register(newClientTool(proxy, j.Name, j.Spec))
+			var toolOpts []toolOption
+			if j.Serial {
+				toolOpts = append(toolOpts, WithSerialToolCalls())
+			}
+			if j.TimeoutMS > 0 {
+				toolOpts = append(toolOpts, WithToolTimeout(time.Duration(j.TimeoutMS)*time.Millisecond))
+			}
+			tool := newClientTool(job, j.Name, j.Spec, toolOpts...)
+			if err := validateToolSpec(model, tool); err != nil {
+				writeWSError(proxy, "error registering client tool", err)
+				return
+			}
+			if err := opts.Tools.Register(tool); err != nil {
+				writeWSError(proxy, "error registering client tool", err)
+				return
+			}
 		}
 	}
-	out := model.StreamCompletion(r.Context(), history, opts)
-	out2 := app.streamBlocks(ctx, chatID, out, titleChan, isFirst)
-	for i := range out2 {
-		msg := jsonrpc.NewNotification("block", i)
-		if err := proxy.write(msg); err != nil {
-			writeWSError(proxy, "error writing block message", err)
-			return
-		}
+	// NOTE: stopping cancels the provider stream and the tools, while the blocks are still persisted
+	out := model.StreamCompletion(genCtx, history, opts)
+	stopped := func() bool { return errors.Is(context.Cause(genCtx), errGenerationStopped) }
+	out2 := app.streamBlocks(jobCtx, chatID, userMessage.GetID(), model.GetModelInfo(), out, titleChan, isFirst, stopped)
+	started = true
+	app.runGenerationJob(job, out2)
+	job.serve(proxy)
+}
+
+func requestToolApproval(ctx context.Context, proxy rpcClient, name, args string) (bool, error) {
+	type request struct {
+		Name string `json:"name"`
+		Args string `json:"args"`
+	}
+	type response struct {
+		Approved bool `json:"approved"`
 	}
+	req, err := json.Marshal(request{Name: name, Args: args})
+	if err != nil {
+		return false, err
+	}
+	res, err := proxy.rpc(ctx, "tool_approval", req)
+	if err != nil {
+		return false, err
+	}
+	var v response
+	if err := json.Unmarshal(res, &v); err != nil {
+		return false, err
+	}
+	return v.Approved, nil
 }
 
 func (app *App) streamBlocks(
-	ctx context.Context, chatID int64, in <-chan Result[Message], titleChan chan string, isFirst bool,
+	ctx context.Context,
+	chatID int64,
+	turnUUID string,
+	modelInfo ModelInfo,
+	in <-chan Result[Message],
+	titleChan chan string,
+	isFirst bool,
+	stopped func() bool,
 ) <-chan Block {
 	begin := time.Now()
 	out1 := make(chan Block)
 	go func() {
 		defer close(out1)
-		var done bool
+		var (
+			done          bool
+			lastAssistant *AssistantMessage
+			lastMessage   Message
+		)
 		blocks := map[string]Block{}
 		toolBlocks := map[string]*ToolBlock{}
+		toolResults := map[string]bool{}
 		for i := range in {
 			if done {
 				continue
 			}
 			if i.Err != nil {
-				logger.Get().Error(fmt.Sprintf("error in stream: %v", i.Err))
 				done = true
+				if stopped() {
+					continue
+				}
+				logger.Get().Error(fmt.Sprintf("error in stream: %v", i.Err))
 				out1 <- NewErrorBlock(-32603, i.Err.Error())
 			} else {
-				if err := app.upsertMessage(ctx, chatID, i.Val); err != nil {
+				if msg, ok := i.Val.(*AssistantMessage); ok {
+					// NOTE: wrapping models (e.g. fallbacks) may have already recorded the answering model
+					if _, ok := msg.GetPersistedMeta("model_id"); !ok {
+						msg.SetPersistedMeta("model_id", modelInfo.ID)
+						msg.SetPersistedMeta("model_name", modelInfo.Name)
+					}
+					if id, _ := msg.GetPersistedMeta("model_id"); id == modelInfo.ID && msg.Usage != nil {
+						cost := modelInfo.Pricing.Cost(*msg.Usage)
+						msg.SetPersistedMeta("cost", strconv.FormatFloat(cost, 'f', -1, 64))
+					}
+				}
+				if err := app.upsertMessage(ctx, chatID, turnUUID, i.Val); err != nil {
 					logger.Get().Error(fmt.Sprintf("error upserting message: %v", err))
 					done = true
 					out1 <- NewErrorBlock(-32603, fmt.Sprintf("error upserting message: %v", err))
 					continue
 				}
+				// NOTE: a message is streamed until the next one begins, so only then is it indexed
+				if lastMessage != nil && lastMessage.GetID() != i.Val.GetID() {
+					app.indexMessage(ctx, chatID, lastMessage.GetID())
+				}
+				lastMessage = i.Val
 				switch i := i.Val.(type) {
 				case *AssistantMessage:
+					lastAssistant = i
+					if limit, ok := i.GetPersistedMeta("tool_limit"); ok {
+						id := i.GetID() + "_tool_limit"
+						if _, ok := blocks[id]; !ok {
+							block := NewNoticeBlock("tool_limit", toolLimitNotice(limit))
+							blocks[id] = block
+							out1 <- block
+						}
+					}
 					if i.Thinking != "" {
 						id := i.GetID() + "_thinking"
 						block, ok := blocks[id].(*ThinkingBlock)
@@ -205,6 +469,7 @@ func (app *App) streamBlocks(
 						block, ok := blocks[id].(*TextBlock)
 						if !ok {
 							block = NewTextBlock("assistant", "")
+							block.Model, _ = i.GetPersistedMeta("model_name")
 							blocks[id] = block
 						}
 						block.Update(i.Content)
@@ -216,6 +481,7 @@ func (app *App) streamBlocks(
 							block, ok := blocks[id].(*ToolBlock)
 							if !ok {
 								block = NewToolBlock("", "")
+								block.CallID = j.CallID
 								blocks[id] = block
 								toolBlocks[j.CallID] = block
 							}
@@ -225,6 +491,7 @@ func (app *App) streamBlocks(
 					}
 				case *ToolMessage:
 					id := i.CallID
+					toolResults[id] = true
 					block, ok := toolBlocks[id]
 					if !ok {
 						continue
@@ -246,12 +513,20 @@ func (app *App) streamBlocks(
 				}
 			}
 		}
+		if stopped() {
+			for _, block := range app.stopMessage(ctx, chatID, turnUUID, lastAssistant, toolBlocks, toolResults) {
+				out1 <- block
+			}
+		}
+		if lastMessage != nil {
+			app.indexMessage(ctx, chatID, lastMessage.GetID())
+		}
 	}()
 	out2 := make(chan Block)
 	go func() {
 		defer close(out2)
 		for i := range out1 {
-			if err := app.upsertBlock(ctx, chatID, i); err != nil {
+			if err := app.upsertBlock(ctx, chatID, turnUUID, i); err != nil {
 				logger.Get().Error(fmt.Sprintf("error upserting block: %v", err))
 			}
 			out2 <- i
@@ -266,6 +541,8 @@ func (app *App) streamBlocks(
 					Title: title,
 				}); err != nil {
 					logger.Get().Error(fmt.Sprintf("error updating chat title: %v", err))
+				} else {
+					app.publishChat(ctx, "chat_updated", chatID)
 				}
 			}
 		}
@@ -273,18 +550,75 @@ func (app *App) streamBlocks(
 	return out2
 }
 
-func (app *App) upsertMessage(ctx context.Context, chatID int64, message Message) error {
+// stopMessage marks the partial answer of a stopped generation as such. Tool calls which never got a
+// result are dropped from the message, as their arguments may be incomplete, and their blocks are
+// marked as cancelled. It returns the blocks to emit.
+func (app *App) stopMessage(
+	ctx context.Context,
+	chatID int64,
+	turnUUID string,
+	msg *AssistantMessage,
+	toolBlocks map[string]*ToolBlock,
+	toolResults map[string]bool,
+) []Block {
+	blocks := []Block{}
+	if msg != nil {
+		toolCalls := make([]AssistantMessageToolCall, 0, len(msg.ToolCalls))
+		for _, j := range msg.ToolCalls {
+			if toolResults[j.CallID] {
+				toolCalls = append(toolCalls, j)
+				continue
+			}
+			if block, ok := toolBlocks[j.CallID]; ok {
+				block.SetError(toolErrorCodeCancelled, errGenerationStopped.Error())
+				blocks = append(blocks, block)
+			}
+		}
+		msg.ToolCalls = toolCalls
+		msg.SetPersistedMeta("stopped", "true")
+		if err := app.upsertMessage(ctx, chatID, turnUUID, msg); err != nil {
+			logger.Get().Error(fmt.Sprintf("error upserting message: %v", err))
+		}
+	}
+	blocks = append(blocks, NewNoticeBlock("stopped", "The answer was interrupted before it was finished."))
+	return blocks
+}
+
+func toolLimitNotice(limit string) string {
+	switch limit {
+	case "calls":
+		return "The maximum number of tool calls was reached, so the answer was generated without further tool use."
+	case "rounds":
+		return "The maximum number of tool rounds was reached, so the answer was generated without further tool use."
+	default:
+		return "A tool use limit was reached, so the answer was generated without further tool use."
+	}
+}
+
+func (app *App) upsertMessage(ctx context.Context, chatID int64, parentUUID string, message Message) error {
 	return app.upsertChatEvent(ctx,
 		chatID,
+		parentUUID,
 		message.GetID(),
 		fmt.Sprintf("message.%s", message.GetType()),
 		util.Must(message.MarshalJSON()),
 	)
 }
 
-func (app *App) upsertBlock(ctx context.Context, chatID int64, block Block) error {
+// indexMessage updates the search index with the final content of the message.
+func (app *App) indexMessage(ctx context.Context, chatID int64, messageUUID string) {
+	if err := app.repo.IndexChatEvent(ctx, repo.IndexChatEventArgs{
+		ChatID: chatID,
+		UUID:   messageUUID,
+	}); err != nil {
+		logger.Get().Error(fmt.Sprintf("error indexing message: %v", err))
+	}
+}
+
+func (app *App) upsertBlock(ctx context.Context, chatID int64, parentUUID string, block Block) error {
 	return app.upsertChatEvent(ctx,
 		chatID,
+		parentUUID,
 		block.GetID(),
 		fmt.Sprintf("block.%s", block.GetType()),
 		util.Must(block.MarshalJSON()),
@@ -292,79 +626,157 @@ func (app *App) upsertBlock(ctx context.Context, chatID int64, block Block) erro
 }
 
 func (app *App) upsertChatEvent(
-	ctx context.Context, chatID int64, eventUUID string, eventKind string, eventContent []byte,
+	ctx context.Context, chatID int64, parentUUID string, eventUUID string, eventKind string, eventContent []byte,
 ) error {
 	if _, err := app.repo.CreateChatEvent(ctx, repo.CreateChatEventArgs{
-		ChatID:  chatID,
-		UUID:    eventUUID,
-		Kind:    eventKind,
-		Content: eventContent,
+		ChatID:     chatID,
+		ParentUUID: parentUUID,
+		UUID:       eventUUID,
+		Kind:       eventKind,
+		Content:    eventContent,
 	}); err != nil {
 		return err
 	}
+	if strings.HasPrefix(eventKind, "block.") {
+		app.publishBlock(chatID, eventContent)
+	}
 	return nil
 }
 
-func (app *App) isFirstUserMessage(ctx context.Context, chatID int64) (bool, error) {
+// findUserTurn finds the user message identified either by its own ID or by the ID of its text block,
+// together with its parent and its text block.
+func (app *App) findUserTurn(
+	ctx context.Context, chatID int64, eventID string,
+) (*UserMessage, string, *TextBlock, error) {
 	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{
-		ChatID:     chatID,
-		KindPrefix: "message.user",
+		ChatID:      chatID,
+		AllBranches: true,
 	})
 	if err != nil {
-		return false, err
+		return nil, "", nil, err
+	}
+	var (
+		messageUUID string
+		userMessage *UserMessage
+		parentUUID  string
+		userBlock   *TextBlock
+	)
+	for _, i := range events.Items {
+		if i.UUID != eventID {
+			continue
+		}
+		switch i.Kind {
+		case "message.user":
+			messageUUID = i.UUID
+		case "block.text":
+			// NOTE: blocks are parented to the user message of their turn
+			messageUUID = i.ParentUUID
+		}
 	}
-	return len(events.Items) == 0, nil
+	if messageUUID == "" {
+		return nil, "", nil, fmt.Errorf("user message for event %q not found", eventID)
+	}
+	for _, i := range events.Items {
+		switch {
+		case i.UUID == messageUUID && i.Kind == "message.user":
+			message, err := parseMessage(i.Content)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			userMessage, _ = message.(*UserMessage)
+			parentUUID = i.ParentUUID
+		case i.ParentUUID == messageUUID && i.Kind == "block.text" && userBlock == nil:
+			block, err := parseBlock(i.Content)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			if textBlock, ok := block.(*TextBlock); ok && textBlock.Role == "user" {
+				userBlock = textBlock
+			}
+		}
+	}
+	if userMessage == nil {
+		return nil, "", nil, fmt.Errorf("user message for event %q not found", eventID)
+	}
+	return userMessage, parentUUID, userBlock, nil
 }
 
+var chatTitleSystemPrompt = "You are a helpful assistant that generates concise, descriptive titles based on the user's first chat message. " +
+	"IMPORTANT: Use sentence case, NOT title case. This means only capitalize the first word and proper nouns like names of people, places, or brands. " +
+	"Create a short chat title (max 80 characters) that captures the essence of the user's initial message and the chat's context. " +
+	"DO NOT include emojis, special characters or punctuation at the end of the title. " +
+	"Respond with just the title, no quotes or explanations. Respond in the same language as the message. " +
+	"Your response will be used directly as the chat title." +
+	chatTitleExamples
+
+// chatRenameSystemPrompt titles a chat from its whole conversation, which is wrapped in a
+// <conversation> element of <user> and <assistant> messages.
+var chatRenameSystemPrompt = "You are a helpful assistant that generates concise, descriptive titles based on a chat's conversation. " +
+	"IMPORTANT: Use sentence case, NOT title case. This means only capitalize the first word and proper nouns like names of people, places, or brands. " +
+	"Create a short chat title (max 80 characters) that captures the essence of the user's messages and what the conversation is about as a whole. " +
+	"DO NOT include emojis, special characters or punctuation at the end of the title. " +
+	"Respond with just the title, no quotes or explanations. Respond in the same language as the user's messages. " +
+	"Your response will be used directly as the chat title." +
+	chatTitleExamples
+
+const chatTitleExamples = "\n\nExamples of CORRECT titles (sentence case):\n" +
+	"- \"How to make a perfect omelette\" (NOT \"How To Make A Perfect Omelette\")\n" +
+	"- \"Elokuvasuosituksia draaman ystäville\" (NOT \"Elokuvasuosituksia Draaman Ystäville\")\n" +
+	"- \"Z-value spike implications\" (NOT \"Z-Value Spike Implications\")\n" +
+	"- \"Planning a trip to London\" (NOT \"Planning A Trip To London\")\n" +
+	"- \"Ways to improve productivity at work\" (NOT \"Ways To Improve Productivity At Work\")\n" +
+	"\n\nRemember: Only capitalize the first word and proper nouns. Every other word should be lowercase."
+
 func (app *App) generateChatTitle(ctx context.Context, content string) chan string {
 	result := make(chan string, 1)
 	go func() {
 		defer close(result)
-		// TODO: allow setting the "small but capable" model in the config
-		titleModel := app.getSmallButCapableModel()
-		if titleModel == nil {
-			logger.Get().Error("no model found for title generation")
-			return
-		}
-		systemPrompt := "You are a helpful assistant that generates concise, descriptive titles based on the user's first chat message. " +
-			"IMPORTANT: Use sentence case, NOT title case. This means only capitalize the first word and proper nouns like names of people, places, or brands. " +
-			"Create a short chat title (max 80 characters) that captures the essence of the user's initial message and the chat's context. " +
-			"DO NOT include emojis, special characters or punctuation at the end of the title. " +
-			"Respond with just the title, no quotes or explanations. Respond in the same language as the message. " +
-			"Your response will be used directly as the chat title." +
-			"\n\nExamples of CORRECT titles (sentence case):\n" +
-			"- \"How to make a perfect omelette\" (NOT \"How To Make A Perfect Omelette\")\n" +
-			"- \"Elokuvasuosituksia draaman ystäville\" (NOT \"Elokuvasuosituksia Draaman Ystäville\")\n" +
-			"- \"Z-value spike implications\" (NOT \"Z-Value Spike Implications\")\n" +
-			"- \"Planning a trip to London\" (NOT \"Planning A Trip To London\")\n" +
-			"- \"Ways to improve productivity at work\" (NOT \"Ways To Improve Productivity At Work\")\n" +
-			"\n\nRemember: Only capitalize the first word and proper nouns. Every other word should be lowercase."
-		history := []Message{
-			NewSystemMessage(systemPrompt),
-			NewUserMessage("<initial_user_message>\n" + content + "\n<initial_user_message>"),
-		}
-		temp := 0.3
-		opts := GenerationConfig{
-			MaxTokens:   50,
-			Temperature: &temp,
-		}
-		titleStream := titleModel.StreamCompletion(ctx, history, opts)
-		var title string
-		for res := range titleStream {
-			if res.Err != nil {
-				logger.Get().Error(fmt.Sprintf("error generating title: %v", res.Err))
-				continue
-			}
-			if msg, ok := res.Val.(*AssistantMessage); ok && msg.Content != "" {
-				title = msg.Content
-			}
-		}
-		title = strings.TrimSpace(title)
-		if title == "" {
-			logger.Get().Error("generated title is empty")
+		title, err := app.completeChatTitle(ctx, chatTitleSystemPrompt,
+			"<initial_user_message>\n"+content+"\n<initial_user_message>")
+		if err != nil {
+			logger.Get().Error(fmt.Sprintf("error generating title: %v", err))
 			return
 		}
 		result <- title
 	}()
 	return result
 }
+
+// completeChatTitle asks the small but capable model for a title for the given chat content.
+func (app *App) completeChatTitle(ctx context.Context, systemPrompt, content string) (string, error) {
+	// TODO: allow setting the "small but capable" model in the config
+	titleModel := app.getSmallButCapableModel()
+	if titleModel == nil {
+		return "", fmt.Errorf("no model found for title generation")
+	}
+	history := []Message{
+		NewSystemMessage(systemPrompt),
+		NewUserMessage(content),
+	}
+	temp := 0.3
+	opts := GenerationConfig{
+		MaxTokens:   50,
+		Temperature: &temp,
+	}
+	var (
+		title   string
+		lastErr error
+	)
+	for res := range titleModel.StreamCompletion(ctx, history, opts) {
+		if res.Err != nil {
+			lastErr = res.Err
+			continue
+		}
+		if msg, ok := res.Val.(*AssistantMessage); ok && msg.Content != "" {
+			title = msg.Content
+		}
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		if lastErr != nil {
+			return "", lastErr
+		}
+		return "", fmt.Errorf("generated title is empty")
+	}
+	return title, nil
+}
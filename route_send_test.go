@@ -0,0 +1,72 @@
+package juttele
+
+import (
+	"context"
+	"testing"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+func TestStreamBlocksToolLimit(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t)
+	chatID := createTestChat(t, app, "u1", [][4]string{{"", "u1", "message.user", "user"}})
+	tools := NewToolCatalog()
+	tools.Register(NewFuncTool("echo", []byte(`{"name":"echo"}`), func(ctx context.Context, args string) (string, error) {
+		return args, nil
+	}))
+	var (
+		requests []GenerationConfig
+		history  []Message
+	)
+	opts := GenerationConfig{Tools: tools, MaxToolCalls: 3}
+	in := streamWithTools(ctx, opts, &history, fakeToolRequest(2, 5, &requests))
+	for range app.streamBlocks(ctx, chatID, "u1", ModelInfo{ID: "fake", Name: "Fake"}, in, nil, false,
+		func() bool { return false }) {
+	}
+
+	events, err := app.repo.ListChatEvents(ctx, repo.ListChatEventsArgs{ChatID: chatID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		notices []*NoticeBlock
+		limited int
+		final   *AssistantMessage
+	)
+	for _, i := range events.Items {
+		switch i.Kind {
+		case "message.assistant":
+			message, err := parseMessage(i.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			final = message.(*AssistantMessage)
+		case "block.notice", "block.tool":
+			block, err := parseBlock(i.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch block := block.(type) {
+			case *NoticeBlock:
+				notices = append(notices, block)
+			case *ToolBlock:
+				if block.Error != nil && block.Error.Code == toolErrorCodeLimit {
+					limited++
+				}
+			}
+		}
+	}
+	if len(notices) != 1 || notices[0].Code != "tool_limit" || notices[0].Message != toolLimitNotice("calls") {
+		t.Errorf("notices = %+v, want a single tool_limit notice", notices)
+	}
+	if limited != 1 {
+		t.Errorf("got %d tool blocks over the limit, want 1", limited)
+	}
+	if final == nil {
+		t.Fatal("no assistant message persisted")
+	}
+	if limit, _ := final.GetPersistedMeta("tool_limit"); limit != "calls" || len(final.ToolCalls) != 0 {
+		t.Errorf("final message = %+v, want a tool-less answer with tool_limit %q", final, "calls")
+	}
+}
@@ -0,0 +1,110 @@
+package juttele
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/markusylisiurunen/juttele/internal/logger"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+type (
+	usageResponse_Usage struct {
+		Messages         int64   `json:"messages"`
+		InputTokens      int64   `json:"input_tokens"`
+		OutputTokens     int64   `json:"output_tokens"`
+		CachedTokens     int64   `json:"cached_tokens"`
+		CacheWriteTokens int64   `json:"cache_write_tokens"`
+		ReasoningTokens  int64   `json:"reasoning_tokens"`
+		Cost             float64 `json:"cost"`
+	}
+	usageResponse_Chat struct {
+		ChatID int64 `json:"chat_id"`
+		usageResponse_Usage
+	}
+	usageResponse_Model struct {
+		ModelID   string `json:"model_id"`
+		ModelName string `json:"model_name"`
+		usageResponse_Usage
+	}
+	usageResponse struct {
+		Total  usageResponse_Usage   `json:"total"`
+		Chats  []usageResponse_Chat  `json:"chats"`
+		Models []usageResponse_Model `json:"models"`
+	}
+)
+
+func (u *usageResponse_Usage) add(other usageResponse_Usage) {
+	u.Messages += other.Messages
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CachedTokens += other.CachedTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.Cost += other.Cost
+}
+
+func (app *App) usageRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var chatID int64
+	if v := r.URL.Query().Get("chat_id"); v != "" {
+		var err error
+		chatID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error parsing chat ID: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	usage, err := app.repo.ListUsage(ctx, repo.ListUsageArgs{ChatID: chatID})
+	if err != nil {
+		logger.Get().Error(fmt.Sprintf("error listing usage: %v", err))
+		http.Error(w, fmt.Sprintf("error listing usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+	modelNames := map[string]string{}
+	for _, model := range app.models {
+		info := model.GetModelInfo()
+		modelNames[info.ID] = info.Name
+	}
+	var v usageResponse
+	v.Chats = make([]usageResponse_Chat, 0)
+	v.Models = make([]usageResponse_Model, 0)
+	chatIdx := map[int64]int{}
+	modelIdx := map[string]int{}
+	for _, i := range usage.Items {
+		item := usageResponse_Usage{
+			Messages:         i.Messages,
+			InputTokens:      i.InputTokens,
+			OutputTokens:     i.OutputTokens,
+			CachedTokens:     i.CachedTokens,
+			CacheWriteTokens: i.CacheWriteTokens,
+			ReasoningTokens:  i.ReasoningTokens,
+			Cost:             i.Cost,
+		}
+		v.Total.add(item)
+		if _, ok := chatIdx[i.ChatID]; !ok {
+			chatIdx[i.ChatID] = len(v.Chats)
+			v.Chats = append(v.Chats, usageResponse_Chat{ChatID: i.ChatID})
+		}
+		v.Chats[chatIdx[i.ChatID]].add(item)
+		if _, ok := modelIdx[i.ModelID]; !ok {
+			modelName, ok := modelNames[i.ModelID]
+			if !ok {
+				modelName = i.ModelName
+			}
+			modelIdx[i.ModelID] = len(v.Models)
+			v.Models = append(v.Models, usageResponse_Model{
+				ModelID:   i.ModelID,
+				ModelName: modelName,
+			})
+		}
+		v.Models[modelIdx[i.ModelID]].add(item)
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+	}
+}
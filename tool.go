@@ -3,6 +3,7 @@ package juttele
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -38,6 +39,13 @@ func (tc *ToolCatalog) Register(tool Tool) error {
 	if _, ok := tc.tools[tool.Name()]; ok {
 		return fmt.Errorf("tool %q already registered", tool.Name())
 	}
+	spec, err := parseToolSpec(tool.Spec())
+	if err != nil {
+		return err
+	}
+	if spec.Name != tool.Name() {
+		return fmt.Errorf("tool %q has a spec named %q", tool.Name(), spec.Name)
+	}
 	tc.tools[tool.Name()] = tool
 	tc.order = append(tc.order, tool.Name())
 	return nil
@@ -59,7 +67,20 @@ func (tc *ToolCatalog) List() []Tool {
 	return out
 }
 
+func (tc *ToolCatalog) Get(name string) (Tool, bool) {
+	if tc == nil {
+		return nil, false
+	}
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	tool, ok := tc.tools[name]
+	return tool, ok
+}
+
 func (tc *ToolCatalog) Call(ctx context.Context, name, args string) (string, error) {
+	if tc == nil {
+		return "", fmt.Errorf("tool %q not found", name)
+	}
 	tc.mux.RLock()
 	tool, ok := tc.tools[name]
 	tc.mux.RUnlock()
@@ -75,14 +96,80 @@ type Tool interface {
 	Call(context.Context, string) (string, error)
 }
 
+const (
+	toolErrorCodeInternal  int64 = -32603
+	toolErrorCodeTimeout   int64 = -32001
+	toolErrorCodeCancelled int64 = -32002
+	toolErrorCodeLimit     int64 = -32003
+)
+
+var (
+	errToolCallTimeout     = errors.New("the tool call timed out")
+	errToolCallCancelled   = errors.New("the tool call was cancelled by the user")
+	errToolApprovalTimeout = errors.New("the tool call was not approved in time")
+	errGenerationStopped   = errors.New("the generation was stopped by the user")
+)
+
+const (
+	defaultToolTimeout         = 60 * time.Second
+	defaultClientToolTimeout   = 10 * time.Second
+	defaultToolApprovalTimeout = 5 * time.Minute
+)
+
+// ToolOptions controls how the calls of a tool are executed. Tools implementing
+// `ToolOptions() ToolOptions` opt into them, and the zero value is used for all other tools.
+type ToolOptions struct {
+	// Approval makes the user approve each call before it runs.
+	Approval bool
+	// Serial runs the calls one at a time and in order, after the tool calls running in parallel.
+	Serial bool
+	// Timeout limits the duration of each call, or the default timeout is used if zero.
+	Timeout time.Duration
+}
+
+type toolOption func(*ToolOptions)
+
+func WithToolApproval() toolOption {
+	return func(o *ToolOptions) {
+		o.Approval = true
+	}
+}
+
+func WithToolTimeout(timeout time.Duration) toolOption {
+	return func(o *ToolOptions) {
+		o.Timeout = timeout
+	}
+}
+
+func WithSerialToolCalls() toolOption {
+	return func(o *ToolOptions) {
+		o.Serial = true
+	}
+}
+
+func getToolOptions(tool Tool) ToolOptions {
+	if t, ok := tool.(interface{ ToolOptions() ToolOptions }); ok {
+		return t.ToolOptions()
+	}
+	return ToolOptions{}
+}
+
 type funcTool struct {
 	name string
 	spec []byte
 	fn   func(context.Context, string) (string, error)
+	opts ToolOptions
 }
 
-func newFuncTool(name string, spec []byte, fn func(context.Context, string) (string, error)) Tool {
-	return &funcTool{name: name, spec: spec, fn: fn}
+// NewFuncTool creates a tool calling the given function with the JSON arguments of each call.
+func NewFuncTool(
+	name string, spec []byte, fn func(context.Context, string) (string, error), opts ...toolOption,
+) Tool {
+	t := &funcTool{name: name, spec: spec, fn: fn}
+	for _, opt := range opts {
+		opt(&t.opts)
+	}
+	return t
 }
 
 func (r *funcTool) Name() string {
@@ -97,14 +184,23 @@ func (r *funcTool) Call(ctx context.Context, args string) (string, error) {
 	return r.fn(ctx, args)
 }
 
+func (r *funcTool) ToolOptions() ToolOptions {
+	return r.opts
+}
+
 type clientTool struct {
-	proxy *webSocketProxy
+	proxy rpcClient
 	name  string
 	spec  []byte
+	opts  ToolOptions
 }
 
-func newClientTool(proxy *webSocketProxy, name string, spec []byte) Tool {
-	return &clientTool{proxy: proxy, name: name, spec: spec}
+func newClientTool(proxy rpcClient, name string, spec []byte, opts ...toolOption) Tool {
+	t := &clientTool{proxy: proxy, name: name, spec: spec, opts: ToolOptions{Timeout: defaultClientToolTimeout}}
+	for _, opt := range opts {
+		opt(&t.opts)
+	}
+	return t
 }
 
 func (r *clientTool) Name() string {
@@ -115,9 +211,11 @@ func (r *clientTool) Spec() []byte {
 	return r.spec
 }
 
+func (r *clientTool) ToolOptions() ToolOptions {
+	return r.opts
+}
+
 func (r *clientTool) Call(ctx context.Context, args string) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
 	type request struct {
 		Name string `json:"name"`
 		Args string `json:"args"`
@@ -136,3 +234,41 @@ func (r *clientTool) Call(ctx context.Context, args string) (string, error) {
 	}
 	return v, nil
 }
+
+// ToolCallRegistry keeps track of in-flight tool calls so that they can be cancelled by their call ID.
+type ToolCallRegistry struct {
+	mu    sync.Mutex
+	calls map[string]context.CancelCauseFunc
+}
+
+func NewToolCallRegistry() *ToolCallRegistry {
+	return &ToolCallRegistry{calls: make(map[string]context.CancelCauseFunc)}
+}
+
+func (r *ToolCallRegistry) start(ctx context.Context, callID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if r == nil {
+		return ctx, func() { cancel(nil) }
+	}
+	r.mu.Lock()
+	r.calls[callID] = cancel
+	r.mu.Unlock()
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.calls, callID)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel cancels the in-flight tool call with the given call ID and reports whether one was found.
+func (r *ToolCallRegistry) Cancel(callID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.calls[callID]
+	if ok {
+		cancel(errToolCallCancelled)
+		delete(r.calls, callID)
+	}
+	return ok
+}
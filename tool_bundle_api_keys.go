@@ -49,7 +49,7 @@ func (m *apiKeyToolBundle) createAPIKey() Tool {
 	}
 }
 	`
-	return newFuncTool(
+	return NewFuncTool(
 		"create_api_key",
 		[]byte(strings.TrimSpace(spec)),
 		func(ctx context.Context, args string) (string, error) {
@@ -72,6 +72,7 @@ func (m *apiKeyToolBundle) createAPIKey() Tool {
 			out, err := json.Marshal(map[string]any{"api_key": apiKeyUUID})
 			return string(out), err
 		},
+		WithToolApproval(),
 	)
 }
 
@@ -48,7 +48,7 @@ func (m *memoryToolBundle) listMemoriesTool() Tool {
 	}
 }
 	`
-	return newFuncTool(
+	return NewFuncTool(
 		"list_memories",
 		[]byte(strings.TrimSpace(spec)),
 		func(ctx context.Context, args string) (string, error) {
@@ -103,7 +103,7 @@ func (m *memoryToolBundle) saveMemoryTool() Tool {
 	}
 }
 	`
-	return newFuncTool(
+	return NewFuncTool(
 		"save_memory",
 		[]byte(strings.TrimSpace(spec)),
 		func(ctx context.Context, args string) (string, error) {
@@ -130,6 +130,7 @@ func (m *memoryToolBundle) saveMemoryTool() Tool {
 			out, err := json.Marshal(map[string]any{"ok": true})
 			return string(out), err
 		},
+		WithSerialToolCalls(),
 	)
 }
 
@@ -155,7 +156,7 @@ func (m *memoryToolBundle) updateMemoryTool() Tool {
 	}
 }
 	`
-	return newFuncTool(
+	return NewFuncTool(
 		"update_memory",
 		[]byte(strings.TrimSpace(spec)),
 		func(ctx context.Context, args string) (string, error) {
@@ -181,6 +182,7 @@ func (m *memoryToolBundle) updateMemoryTool() Tool {
 			out, err := json.Marshal(map[string]any{"ok": true})
 			return string(out), err
 		},
+		WithSerialToolCalls(),
 	)
 }
 
@@ -202,7 +204,7 @@ func (m *memoryToolBundle) deleteMemoryTool() Tool {
 	}
 }
 	`
-	return newFuncTool(
+	return NewFuncTool(
 		"delete_memory",
 		[]byte(strings.TrimSpace(spec)),
 		func(ctx context.Context, args string) (string, error) {
@@ -226,6 +228,8 @@ func (m *memoryToolBundle) deleteMemoryTool() Tool {
 			out, err := json.Marshal(map[string]any{"ok": true})
 			return string(out), err
 		},
+		WithToolApproval(),
+		WithSerialToolCalls(),
 	)
 }
 
@@ -0,0 +1,293 @@
+package juttele
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var toolNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+type toolSpecTranslator interface {
+	spec([]byte) ([]byte, error)
+}
+
+func validateToolSpec(model Model, tool Tool) error {
+	if t, ok := model.(toolSpecTranslator); ok {
+		if _, err := t.spec(tool.Spec()); err != nil {
+			return fmt.Errorf("tool %q is not supported by model %q: %w", tool.Name(), model.GetModelInfo().ID, err)
+		}
+	}
+	return nil
+}
+
+type toolSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+func parseToolSpec(spec []byte) (toolSpec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(spec))
+	decoder.UseNumber()
+	var v toolSpec
+	if err := decoder.Decode(&v); err != nil {
+		return toolSpec{}, fmt.Errorf("invalid tool spec: %w", err)
+	}
+	if !toolNameRegexp.MatchString(v.Name) {
+		return toolSpec{}, fmt.Errorf("invalid tool name %q", v.Name)
+	}
+	if v.Parameters == nil {
+		v.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	if v.Parameters["type"] != "object" {
+		return toolSpec{}, fmt.Errorf("tool %q: parameters must be an object schema", v.Name)
+	}
+	return v, nil
+}
+
+// checkTopLevelKeywords rejects the keywords a provider does not accept at the top level of the
+// parameters, as they could let the arguments be something other than an object.
+func (v toolSpec) checkTopLevelKeywords(keywords ...string) error {
+	for _, keyword := range keywords {
+		if _, ok := v.Parameters[keyword]; ok {
+			return fmt.Errorf("tool %q: keyword %q is not supported at the top level", v.Name, keyword)
+		}
+	}
+	return nil
+}
+
+func openAIToolSpec(spec []byte) ([]byte, error) {
+	v, err := parseToolSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkTopLevelKeywords("anyOf", "oneOf", "allOf", "enum", "not"); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func anthropicToolSpec(spec []byte) ([]byte, error) {
+	type AnthropicToolSpec struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		InputSchema map[string]any `json:"input_schema"`
+	}
+	v, err := parseToolSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkTopLevelKeywords("anyOf", "oneOf", "allOf"); err != nil {
+		return nil, err
+	}
+	return json.Marshal(AnthropicToolSpec{
+		Name:        v.Name,
+		Description: v.Description,
+		InputSchema: v.Parameters,
+	})
+}
+
+func googleToolSpec(spec []byte) ([]byte, error) {
+	type GoogleToolSpec struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters,omitempty"`
+	}
+	v, err := parseToolSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkTopLevelKeywords("anyOf", "oneOf", "allOf", "not"); err != nil {
+		return nil, err
+	}
+	out := GoogleToolSpec{Name: v.Name, Description: v.Description}
+	// NOTE: Gemini rejects object schemas without any properties, so parameterless tools omit them
+	if properties, ok := v.Parameters["properties"].(map[string]any); ok && len(properties) > 0 {
+		t := &googleSchemaTranslator{root: v.Parameters}
+		params, err := t.translate(v.Parameters, "#", nil)
+		if err != nil {
+			return nil, fmt.Errorf("tool %q: %w", v.Name, err)
+		}
+		out.Parameters = params
+	}
+	return json.Marshal(out)
+}
+
+// googleSchemaTranslator converts JSON Schema into the OpenAPI 3.0 subset accepted by Gemini.
+type googleSchemaTranslator struct {
+	root map[string]any
+}
+
+var googleSchemaKeywords = []string{
+	"type", "format", "title", "description", "nullable", "enum", "items", "minItems", "maxItems",
+	"properties", "required", "minProperties", "maxProperties", "minLength", "maxLength", "pattern",
+	"minimum", "maximum", "anyOf", "default", "example", "propertyOrdering",
+}
+
+var googleSchemaIgnoredKeywords = []string{
+	"$schema", "$id", "$comment", "$defs", "definitions", "additionalProperties", "examples",
+	"deprecated", "readOnly", "writeOnly", "contentEncoding", "contentMediaType",
+}
+
+func (t *googleSchemaTranslator) translate(
+	schema map[string]any, path string, refs []string,
+) (map[string]any, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if slices.Contains(refs, ref) {
+			return nil, fmt.Errorf("recursive $ref %q at %q cannot be represented", ref, path)
+		}
+		resolved, err := t.resolve(ref)
+		if err != nil {
+			return nil, fmt.Errorf("%w at %q", err, path)
+		}
+		merged := make(map[string]any, len(resolved)+len(schema))
+		for k, v := range resolved {
+			merged[k] = v
+		}
+		for k, v := range schema {
+			if k != "$ref" {
+				merged[k] = v
+			}
+		}
+		return t.translate(merged, path, append(refs, ref))
+	}
+	out := make(map[string]any, len(schema))
+	for k, v := range schema {
+		switch {
+		case slices.Contains(googleSchemaIgnoredKeywords, k) || strings.HasPrefix(k, "x-"):
+			continue
+		case k == "type":
+			typ, nullable, err := t.translateType(v, path)
+			if err != nil {
+				return nil, err
+			}
+			out["type"] = typ
+			if nullable {
+				out["nullable"] = true
+			}
+		case k == "const":
+			out["enum"] = []any{v}
+		case k == "oneOf" || k == "anyOf":
+			items, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("keyword %q at %q must be an array", k, path)
+			}
+			translated := make([]any, 0, len(items))
+			for idx, item := range items {
+				item, ok := item.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("keyword %q at %q must contain schemas", k, path)
+				}
+				s, err := t.translate(item, fmt.Sprintf("%s/%s/%d", path, k, idx), refs)
+				if err != nil {
+					return nil, err
+				}
+				translated = append(translated, s)
+			}
+			out["anyOf"] = translated
+		case k == "properties":
+			properties, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("keyword %q at %q must be an object", k, path)
+			}
+			translated := make(map[string]any, len(properties))
+			for name, property := range properties {
+				property, ok := property.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("property %q at %q must be a schema", name, path)
+				}
+				s, err := t.translate(property, path+"/properties/"+name, refs)
+				if err != nil {
+					return nil, err
+				}
+				translated[name] = s
+			}
+			out["properties"] = translated
+		case k == "items":
+			items, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("keyword %q at %q must be a single schema", k, path)
+			}
+			s, err := t.translate(items, path+"/items", refs)
+			if err != nil {
+				return nil, err
+			}
+			out["items"] = s
+		case slices.Contains(googleSchemaKeywords, k):
+			out[k] = v
+		default:
+			return nil, fmt.Errorf("keyword %q at %q cannot be represented", k, path)
+		}
+	}
+	if enum, ok := out["enum"].([]any); ok {
+		for _, i := range enum {
+			if _, ok := i.(string); !ok {
+				return nil, fmt.Errorf("non-string enum value %v at %q cannot be represented", i, path)
+			}
+		}
+		if _, ok := out["type"]; !ok {
+			out["type"] = "string"
+		}
+	}
+	return out, nil
+}
+
+func (t *googleSchemaTranslator) translateType(v any, path string) (string, bool, error) {
+	switch v := v.(type) {
+	case string:
+		return v, false, nil
+	case []any:
+		var (
+			types    []string
+			nullable bool
+		)
+		for _, i := range v {
+			s, ok := i.(string)
+			if !ok {
+				return "", false, fmt.Errorf("invalid type %v at %q", i, path)
+			}
+			if s == "null" {
+				nullable = true
+				continue
+			}
+			types = append(types, s)
+		}
+		if len(types) != 1 {
+			return "", false, fmt.Errorf("union type %v at %q cannot be represented", v, path)
+		}
+		return types[0], nullable, nil
+	default:
+		return "", false, fmt.Errorf("invalid type %v at %q", v, path)
+	}
+}
+
+func (t *googleSchemaTranslator) resolve(ref string) (map[string]any, error) {
+	if ref == "#" {
+		return t.root, nil
+	}
+	var (
+		current any = t.root
+		found       = strings.HasPrefix(ref, "#/")
+	)
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		if !found {
+			break
+		}
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		m, ok := current.(map[string]any)
+		if !ok {
+			found = false
+			break
+		}
+		current, found = m[segment]
+	}
+	schema, ok := current.(map[string]any)
+	if !found || !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+	return schema, nil
+}
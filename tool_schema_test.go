@@ -0,0 +1,225 @@
+package juttele
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseToolSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "missing parameters",
+			spec: `{"name":"ping"}`,
+			want: `{"name":"ping","parameters":{"properties":{},"type":"object"}}`,
+		},
+		{
+			name: "numbers are kept as is",
+			spec: `{"name":"ping","parameters":{"type":"object","properties":{"n":{"type":"integer","maximum":10000000000000000001}}}}`,
+			want: `{"name":"ping","parameters":{"properties":{"n":{"maximum":10000000000000000001,"type":"integer"}},"type":"object"}}`,
+		},
+		{name: "invalid name", spec: `{"name":"get weather"}`, wantErr: `invalid tool name "get weather"`},
+		{name: "invalid json", spec: `{"name":`, wantErr: "invalid tool spec"},
+		{
+			name:    "non-object parameters",
+			spec:    `{"name":"ping","parameters":{"type":"string"}}`,
+			wantErr: "parameters must be an object schema",
+		},
+		{
+			name: "top-level keywords are left to the providers",
+			spec: `{"name":"ping","parameters":{"type":"object","anyOf":[]}}`,
+			want: `{"name":"ping","parameters":{"anyOf":[],"type":"object"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := parseToolSpec([]byte(tt.spec))
+			var got []byte
+			if err == nil {
+				got, err = json.Marshal(v)
+			}
+			checkToolSpec(t, string(got), err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestOpenAIToolSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "schema is passed through",
+			params: `{"type":"object","properties":{"a":{"type":"integer","enum":[1,2]}},"additionalProperties":false}`,
+			want:   `{"name":"test","parameters":{"additionalProperties":false,"properties":{"a":{"enum":[1,2],"type":"integer"}},"type":"object"}}`,
+		},
+		{
+			name:   "nested anyOf",
+			params: `{"type":"object","properties":{"a":{"anyOf":[{"type":"string"},{"type":"integer"}]}}}`,
+			want:   `{"name":"test","parameters":{"properties":{"a":{"anyOf":[{"type":"string"},{"type":"integer"}]}},"type":"object"}}`,
+		},
+		{name: "top-level anyOf", params: `{"type":"object","anyOf":[]}`, wantErr: `keyword "anyOf" is not supported`},
+		{name: "top-level oneOf", params: `{"type":"object","oneOf":[]}`, wantErr: `keyword "oneOf" is not supported`},
+		{name: "top-level allOf", params: `{"type":"object","allOf":[]}`, wantErr: `keyword "allOf" is not supported`},
+		{name: "top-level enum", params: `{"type":"object","enum":[]}`, wantErr: `keyword "enum" is not supported`},
+		{name: "top-level not", params: `{"type":"object","not":{}}`, wantErr: `keyword "not" is not supported`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := openAIToolSpec([]byte(`{"name":"test","parameters":` + tt.params + `}`))
+			checkToolSpec(t, string(got), err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestAnthropicToolSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "parameters become the input schema",
+			params: `{"type":"object","properties":{"a":{"type":["string","null"]}},"required":["a"]}`,
+			want:   `{"name":"test","input_schema":{"properties":{"a":{"type":["string","null"]}},"required":["a"],"type":"object"}}`,
+		},
+		{
+			name:   "top-level not",
+			params: `{"type":"object","properties":{},"not":{"required":["a"]}}`,
+			want:   `{"name":"test","input_schema":{"not":{"required":["a"]},"properties":{},"type":"object"}}`,
+		},
+		{name: "top-level anyOf", params: `{"type":"object","anyOf":[]}`, wantErr: `keyword "anyOf" is not supported`},
+		{name: "top-level oneOf", params: `{"type":"object","oneOf":[]}`, wantErr: `keyword "oneOf" is not supported`},
+		{name: "top-level allOf", params: `{"type":"object","allOf":[]}`, wantErr: `keyword "allOf" is not supported`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := anthropicToolSpec([]byte(`{"name":"test","parameters":` + tt.params + `}`))
+			checkToolSpec(t, string(got), err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestGoogleToolSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "no properties",
+			params: `{"type":"object","properties":{}}`,
+			want:   `{"name":"test"}`,
+		},
+		{
+			name:   "ignored keywords are dropped",
+			params: `{"type":"object","properties":{"a":{"type":"string","x-internal":true}},"additionalProperties":false}`,
+			want:   `{"name":"test","parameters":{"properties":{"a":{"type":"string"}},"type":"object"}}`,
+		},
+		{
+			name:   "nullable union type",
+			params: `{"type":"object","properties":{"a":{"type":["string","null"]}}}`,
+			want:   `{"name":"test","parameters":{"properties":{"a":{"nullable":true,"type":"string"}},"type":"object"}}`,
+		},
+		{
+			name:   "const becomes a string enum",
+			params: `{"type":"object","properties":{"a":{"const":"x"}}}`,
+			want:   `{"name":"test","parameters":{"properties":{"a":{"enum":["x"],"type":"string"}},"type":"object"}}`,
+		},
+		{
+			name:   "oneOf becomes anyOf",
+			params: `{"type":"object","properties":{"a":{"oneOf":[{"type":"string"},{"type":"integer"}]}}}`,
+			want:   `{"name":"test","parameters":{"properties":{"a":{"anyOf":[{"type":"string"},{"type":"integer"}]}},"type":"object"}}`,
+		},
+		{
+			name: "$ref is inlined",
+			params: `{"type":"object","$defs":{"city":{"type":"string","description":"A city."}},` +
+				`"properties":{"from":{"$ref":"#/$defs/city"},"to":{"$ref":"#/$defs/city","description":"The destination."}}}`,
+			want: `{"name":"test","parameters":{"properties":{` +
+				`"from":{"description":"A city.","type":"string"},` +
+				`"to":{"description":"The destination.","type":"string"}},"type":"object"}}`,
+		},
+		{
+			name:   "$ref with escaped segments",
+			params: `{"type":"object","$defs":{"a/b":{"type":"string"}},"properties":{"a":{"$ref":"#/$defs/a~1b"}}}`,
+			want:   `{"name":"test","parameters":{"properties":{"a":{"type":"string"}},"type":"object"}}`,
+		},
+		{
+			name: "recursive $ref",
+			params: `{"type":"object","$defs":{"node":{"type":"object","properties":{"next":{"$ref":"#/$defs/node"}}}},` +
+				`"properties":{"head":{"$ref":"#/$defs/node"}}}`,
+			wantErr: `recursive $ref "#/$defs/node" at "#/properties/head/properties/next"`,
+		},
+		{
+			name:    "unresolvable $ref",
+			params:  `{"type":"object","properties":{"a":{"$ref":"#/$defs/missing"}}}`,
+			wantErr: `unresolvable $ref "#/$defs/missing" at "#/properties/a"`,
+		},
+		{
+			name:    "external $ref",
+			params:  `{"type":"object","properties":{"a":{"$ref":"https://example.com/schema.json"}}}`,
+			wantErr: `unresolvable $ref "https://example.com/schema.json"`,
+		},
+		{
+			name:    "non-string enum",
+			params:  `{"type":"object","properties":{"a":{"type":"integer","enum":[1,2]}}}`,
+			wantErr: `non-string enum value 1 at "#/properties/a"`,
+		},
+		{
+			name:    "non-string const",
+			params:  `{"type":"object","properties":{"a":{"const":true}}}`,
+			wantErr: `non-string enum value true at "#/properties/a"`,
+		},
+		{
+			name:    "union type",
+			params:  `{"type":"object","properties":{"a":{"type":["string","integer"]}}}`,
+			wantErr: `union type [string integer] at "#/properties/a" cannot be represented`,
+		},
+		{
+			name:    "top-level anyOf",
+			params:  `{"type":"object","properties":{"a":{"type":"string"}},"anyOf":[{"required":["a"]}]}`,
+			wantErr: `keyword "anyOf" is not supported at the top level`,
+		},
+		{
+			name:    "top-level not",
+			params:  `{"type":"object","properties":{"a":{"type":"string"}},"not":{"required":["a"]}}`,
+			wantErr: `keyword "not" is not supported at the top level`,
+		},
+		{
+			name:    "unsupported keyword",
+			params:  `{"type":"object","properties":{"a":{"type":"array","prefixItems":[]}}}`,
+			wantErr: `keyword "prefixItems" at "#/properties/a" cannot be represented`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := googleToolSpec([]byte(`{"name":"test","parameters":` + tt.params + `}`))
+			checkToolSpec(t, string(got), err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func checkToolSpec(t *testing.T, got string, err error, want, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("error = %v, want %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("spec = %s\nwant   %s", got, want)
+	}
+}
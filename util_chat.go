@@ -0,0 +1,36 @@
+package juttele
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/markusylisiurunen/juttele/internal/repo"
+	"github.com/markusylisiurunen/juttele/internal/util"
+)
+
+// renewChatEventUUIDs gives the events fresh UUIDs, rewriting the parent references and the IDs
+// inside the contents to match, so that the events can be copied into another chat. Parents outside
+// of the given events are dropped. It returns the mapping from the old UUIDs to the new ones.
+func renewChatEventUUIDs(events []repo.ChatEvent) ([]repo.ChatEvent, map[string]string, error) {
+	uuids := make(map[string]string, len(events))
+	for _, i := range events {
+		uuids[i.UUID] = uuid.Must(uuid.NewV7()).String()
+	}
+	out := make([]repo.ChatEvent, 0, len(events))
+	for _, i := range events {
+		var content map[string]json.RawMessage
+		if err := json.Unmarshal(i.Content, &content); err != nil {
+			return nil, nil, fmt.Errorf("error parsing chat event %q: %w", i.UUID, err)
+		}
+		content["id"] = util.Must(json.Marshal(uuids[i.UUID]))
+		out = append(out, repo.ChatEvent{
+			CreatedAt:  i.CreatedAt,
+			ParentUUID: uuids[i.ParentUUID],
+			UUID:       uuids[i.UUID],
+			Kind:       i.Kind,
+			Content:    util.Must(json.Marshal(content)),
+		})
+	}
+	return out, uuids, nil
+}
@@ -0,0 +1,61 @@
+package juttele
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/markusylisiurunen/juttele/internal/repo"
+)
+
+func TestRenewChatEventUUIDs(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []repo.ChatEvent{
+		{CreatedAt: createdAt, ParentUUID: "outside", UUID: "u1", Kind: "message.user",
+			Content: json.RawMessage(`{"id":"u1","type":"user","content":"Hi"}`)},
+		{CreatedAt: createdAt, ParentUUID: "u1", UUID: "b1", Kind: "block.text",
+			Content: json.RawMessage(`{"id":"b1","type":"text","content":"Hello"}`)},
+		{CreatedAt: createdAt, ParentUUID: "u1", UUID: "u2", Kind: "message.user",
+			Content: json.RawMessage(`{"id":"u2","type":"user","content":"Bye"}`)},
+	}
+	out, uuids, err := renewChatEventUUIDs(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(events) || len(uuids) != len(events) {
+		t.Fatalf("got %d events and %d UUIDs, want %d", len(out), len(uuids), len(events))
+	}
+	seen := map[string]bool{}
+	for idx, i := range out {
+		original := events[idx]
+		if i.UUID == original.UUID || i.UUID != uuids[original.UUID] || seen[i.UUID] {
+			t.Errorf("event %d has UUID %q, mapped from %q", idx, i.UUID, original.UUID)
+		}
+		seen[i.UUID] = true
+		if !i.CreatedAt.Equal(original.CreatedAt) || i.Kind != original.Kind {
+			t.Errorf("event %d = %+v, want the fields of %+v", idx, i, original)
+		}
+		var content map[string]string
+		if err := json.Unmarshal(i.Content, &content); err != nil {
+			t.Fatal(err)
+		}
+		if content["id"] != i.UUID {
+			t.Errorf("event %d has content ID %q, want %q", idx, content["id"], i.UUID)
+		}
+		var originalContent map[string]string
+		json.Unmarshal(original.Content, &originalContent)
+		if content["content"] != originalContent["content"] || content["type"] != originalContent["type"] {
+			t.Errorf("event %d has content %s, want it to match %s", idx, i.Content, original.Content)
+		}
+	}
+	if out[0].ParentUUID != "" {
+		t.Errorf("parent outside of the events = %q, want it dropped", out[0].ParentUUID)
+	}
+	if out[1].ParentUUID != out[0].UUID || out[2].ParentUUID != out[0].UUID {
+		t.Errorf("parents = %q and %q, want %q", out[1].ParentUUID, out[2].ParentUUID, out[0].UUID)
+	}
+
+	if _, _, err := renewChatEventUUIDs([]repo.ChatEvent{{UUID: "x", Content: json.RawMessage(`[]`)}}); err == nil {
+		t.Errorf("expected an error for content which is not an object")
+	}
+}
@@ -0,0 +1,73 @@
+package juttele
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       []byte
+}
+
+func newHTTPStatusError(resp *http.Response, body []byte) *httpStatusError {
+	err := &httpStatusError{StatusCode: resp.StatusCode, Body: body}
+	if v := resp.Header.Get("retry-after-ms"); v != "" {
+		if ms, e := strconv.ParseFloat(v, 64); e == nil && ms > 0 {
+			err.RetryAfter = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := resp.Header.Get("retry-after"); v != "" && err.RetryAfter == 0 {
+		if secs, e := strconv.ParseFloat(v, 64); e == nil && secs > 0 {
+			err.RetryAfter = time.Duration(secs * float64(time.Second))
+		} else if t, e := http.ParseTime(v); e == nil {
+			err.RetryAfter = max(0, time.Until(t))
+		}
+	}
+	return err
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+func isTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+			return true
+		}
+		return statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isProviderError reports whether the request failed because of the provider rather than the
+// request itself, e.g. its credentials, credits or the model's availability, so that another
+// provider may well succeed with the same request.
+func isProviderError(err error) bool {
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
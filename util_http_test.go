@@ -0,0 +1,118 @@
+package juttele
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPStatusErrorRetryAfter(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC()
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		// slack allows for the time passing between building the date and parsing it
+		slack time.Duration
+	}{
+		{name: "no header", want: 0},
+		{name: "seconds", headers: map[string]string{"retry-after": "3"}, want: 3 * time.Second},
+		{name: "fractional seconds", headers: map[string]string{"retry-after": "1.5"}, want: 1500 * time.Millisecond},
+		{name: "milliseconds", headers: map[string]string{"retry-after-ms": "250"}, want: 250 * time.Millisecond},
+		{
+			name:    "milliseconds take precedence",
+			headers: map[string]string{"retry-after-ms": "250", "retry-after": "3"},
+			want:    250 * time.Millisecond,
+		},
+		{
+			name:    "invalid milliseconds fall back to seconds",
+			headers: map[string]string{"retry-after-ms": "soon", "retry-after": "3"},
+			want:    3 * time.Second,
+		},
+		{name: "zero seconds", headers: map[string]string{"retry-after": "0"}, want: 0},
+		{name: "negative seconds", headers: map[string]string{"retry-after": "-5"}, want: 0},
+		{name: "invalid value", headers: map[string]string{"retry-after": "later"}, want: 0},
+		{
+			name:    "http date",
+			headers: map[string]string{"retry-after": future.Format(http.TimeFormat)},
+			want:    time.Hour,
+			slack:   2 * time.Second,
+		},
+		{
+			name:    "http date in the past",
+			headers: map[string]string{"retry-after": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+			want:    0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			err := newHTTPStatusError(resp, []byte("slow down"))
+			if err.StatusCode != http.StatusTooManyRequests || string(err.Body) != "slow down" {
+				t.Errorf("error = %+v", err)
+			}
+			if err.RetryAfter < tt.want-tt.slack || err.RetryAfter > tt.want {
+				t.Errorf("retry after = %v, want %v", err.RetryAfter, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "too many requests", err: &httpStatusError{StatusCode: 429}, want: true},
+		{name: "server error", err: &httpStatusError{StatusCode: 503}, want: true},
+		{name: "bad request", err: &httpStatusError{StatusCode: 400}, want: false},
+		{name: "wrapped status", err: fmt.Errorf("request: %w", &httpStatusError{StatusCode: 502}), want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("refused")}, want: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, want: true},
+		{name: "deadline", err: context.DeadlineExceeded, want: true},
+		{name: "cancelled", err: context.Canceled, want: false},
+		{name: "other", err: errors.New("invalid tool spec"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsProviderError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "unauthorized", err: &httpStatusError{StatusCode: 401}, want: true},
+		{name: "payment required", err: &httpStatusError{StatusCode: 402}, want: true},
+		{name: "forbidden", err: &httpStatusError{StatusCode: 403}, want: true},
+		{name: "model not found", err: fmt.Errorf("request: %w", &httpStatusError{StatusCode: 404}), want: true},
+		{name: "bad request", err: &httpStatusError{StatusCode: 400}, want: false},
+		{name: "payload too large", err: &httpStatusError{StatusCode: 413}, want: false},
+		{name: "unprocessable", err: &httpStatusError{StatusCode: 422}, want: false},
+		{name: "other", err: errors.New("invalid tool spec"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProviderError(tt.err); got != tt.want {
+				t.Errorf("isProviderError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,52 +8,215 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+
+	"github.com/google/uuid"
 )
 
 func streamWithTools(
 	ctx context.Context,
-	tools *ToolCatalog,
+	opts GenerationConfig,
 	history *[]Message,
-	request func() <-chan Result[Message],
+	request func(GenerationConfig) <-chan Result[Message],
 ) <-chan Result[Message] {
+	maxRounds := opts.MaxToolRounds
+	if maxRounds <= 0 {
+		maxRounds = defaultMaxToolRounds
+	}
+	maxCalls := opts.MaxToolCalls
+	if maxCalls <= 0 {
+		maxCalls = defaultMaxToolCalls
+	}
 	out := make(chan Result[Message])
 	go func() {
 		defer close(out)
+		var (
+			rounds int
+			calls  int
+			limit  string
+		)
 	llm:
+		reqOpts := opts
+		if limit != "" {
+			// NOTE: the tools stay declared because the history refers to them, they just cannot be called
+			reqOpts.NoToolCalls = true
+		}
 		var last *AssistantMessage
-		for event := range request() {
+		for event := range request(reqOpts) {
 			if event.Err != nil {
 				out <- Err[Message](event.Err)
 				return
 			}
 			if v, ok := event.Val.(*AssistantMessage); ok {
+				if limit != "" {
+					v.SetPersistedMeta("tool_limit", limit)
+				}
 				last = v
 			}
 			out <- event
 		}
-		if last == nil || len(last.ToolCalls) == 0 {
+		if last == nil || len(last.ToolCalls) == 0 || limit != "" {
 			return
 		}
 		*history = append(*history, last)
-		for _, t := range last.ToolCalls {
-			result, err := tools.Call(ctx, t.FuncName, t.FuncArgs)
-			if err != nil {
-				msg := NewToolMessage(t.CallID)
-				msg.SetError(-32603, err.Error())
-				out <- Ok[Message](msg)
-				*history = append(*history, msg)
-			} else {
-				msg := NewToolMessage(t.CallID)
-				msg.SetResult(result)
-				out <- Ok[Message](msg)
-				*history = append(*history, msg)
-			}
+		rounds++
+		allowed := last.ToolCalls[:min(len(last.ToolCalls), maxCalls-calls)]
+		calls += len(allowed)
+		// NOTE: results are emitted in the original call order so that the persisted history matches
+		// what the model saw, even though the calls themselves may finish in any order
+		for msg := range callTools(ctx, opts, allowed) {
+			out <- Ok[Message](msg)
+			*history = append(*history, msg)
+		}
+		for _, t := range last.ToolCalls[len(allowed):] {
+			msg := NewToolMessage(t.CallID)
+			msg.SetError(toolErrorCodeLimit, "tool call limit reached")
+			out <- Ok[Message](msg)
+			*history = append(*history, msg)
+		}
+		switch {
+		case calls >= maxCalls:
+			limit = "calls"
+		case rounds >= maxRounds:
+			limit = "rounds"
 		}
 		goto llm
 	}()
 	return out
 }
 
+// callTool calls the tool within its timeout. Tools which do not respect context cancellation are
+// abandoned, and their eventual result is discarded.
+func callTool(ctx context.Context, tools *ToolCatalog, t AssistantMessageToolCall) (string, error) {
+	timeout := defaultToolTimeout
+	if tool, ok := tools.Get(t.FuncName); ok && getToolOptions(tool).Timeout > 0 {
+		timeout = getToolOptions(tool).Timeout
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errToolCallTimeout)
+	defer cancel()
+	result := make(chan Tuple[string, error], 1)
+	go func() {
+		v, err := tools.Call(ctx, t.FuncName, t.FuncArgs)
+		result <- NewTuple(v, err)
+	}()
+	select {
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	case res := <-result:
+		if res.T2 != nil && ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+		return res.T1, res.T2
+	}
+}
+
+func toolErrorCode(err error) int64 {
+	switch {
+	case errors.Is(err, errToolCallTimeout):
+		return toolErrorCodeTimeout
+	case errors.Is(err, errToolCallCancelled), errors.Is(err, errGenerationStopped):
+		return toolErrorCodeCancelled
+	default:
+		return toolErrorCodeInternal
+	}
+}
+
+func approveToolCall(ctx context.Context, opts GenerationConfig, t AssistantMessageToolCall) error {
+	tool, ok := opts.Tools.Get(t.FuncName)
+	if !ok || !getToolOptions(tool).Approval {
+		return nil
+	}
+	if opts.ApproveToolCall == nil {
+		return fmt.Errorf("tool %q requires approval, but approval is not available", t.FuncName)
+	}
+	timeout := opts.ToolApprovalTimeout
+	if timeout <= 0 {
+		timeout = defaultToolApprovalTimeout
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errToolApprovalTimeout)
+	defer cancel()
+	approved, err := opts.ApproveToolCall(ctx, t.FuncName, t.FuncArgs)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errToolApprovalTimeout) {
+			// NOTE: an unanswered approval request counts as a rejection
+			return fmt.Errorf("the user did not approve the call to tool %q in time", t.FuncName)
+		}
+		return fmt.Errorf("error requesting approval for tool %q: %w", t.FuncName, err)
+	}
+	if !approved {
+		return fmt.Errorf("the user rejected the call to tool %q", t.FuncName)
+	}
+	return nil
+}
+
+const (
+	defaultMaxToolCalls  = 50
+	defaultMaxToolRounds = 10
+)
+
+const defaultToolConcurrency = 4
+
+// callTools executes the tool calls concurrently, up to the configured limit, and yields the results
+// in the original call order. Tools which require serial execution wait for all preceding calls to
+// finish and block the ones after them.
+func callTools(ctx context.Context, opts GenerationConfig, calls []AssistantMessageToolCall) <-chan *ToolMessage {
+	limit := opts.ToolConcurrency
+	if limit <= 0 {
+		limit = defaultToolConcurrency
+	}
+	call := func(t AssistantMessageToolCall) *ToolMessage {
+		msg := NewToolMessage(t.CallID)
+		ctx, done := opts.ToolCalls.start(ctx, t.CallID)
+		defer done()
+		if err := approveToolCall(ctx, opts, t); err != nil {
+			msg.SetError(toolErrorCode(err), err.Error())
+			return msg
+		}
+		result, err := callTool(ctx, opts.Tools, t)
+		if err != nil {
+			msg.SetError(toolErrorCode(err), err.Error())
+		} else {
+			msg.SetResult(result)
+		}
+		return msg
+	}
+	results := make([]*ToolMessage, len(calls))
+	done := make([]chan struct{}, len(calls))
+	for idx := range done {
+		done[idx] = make(chan struct{})
+	}
+	go func() {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, limit)
+		for idx, t := range calls {
+			tool, ok := opts.Tools.Get(t.FuncName)
+			if ok && getToolOptions(tool).Serial {
+				wg.Wait()
+				results[idx] = call(t)
+				close(done[idx])
+				continue
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[idx] = call(t)
+				close(done[idx])
+			}()
+		}
+	}()
+	out := make(chan *ToolMessage)
+	go func() {
+		defer close(out)
+		for idx := range calls {
+			<-done[idx]
+			out <- results[idx]
+		}
+	}()
+	return out
+}
+
 //--------------------------------------------------------------------------------------------------
 
 func streamAnthropic(resp *http.Response) <-chan Result[Message] {
@@ -82,6 +245,20 @@ func streamAnthropic(resp *http.Response) <-chan Result[Message] {
 			PartialJSON string `json:"partial_json"`
 		} `json:"delta"`
 	}
+	type respUsage struct {
+		InputTokens              int64 `json:"input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	}
+	type respMessageStart struct {
+		Message struct {
+			Usage respUsage `json:"usage"`
+		} `json:"message"`
+	}
+	type respMessageDelta struct {
+		Usage respUsage `json:"usage"`
+	}
 	out := make(chan Result[Message])
 	go func() {
 		defer close(out)
@@ -90,6 +267,15 @@ func streamAnthropic(resp *http.Response) <-chan Result[Message] {
 		out <- Ok[Message](msg)
 		toolBuffer := make([]*toolBufferItem, 64)
 		stopReceived := false
+		var usage respUsage
+		setUsage := func() {
+			msg.SetUsage(AssistantMessageUsage{
+				InputTokens:      usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens,
+				OutputTokens:     usage.OutputTokens,
+				CachedTokens:     usage.CacheReadInputTokens,
+				CacheWriteTokens: usage.CacheCreationInputTokens,
+			})
+		}
 		for event := range events {
 			if event.Err != nil {
 				out <- Err[Message](event.Err)
@@ -98,6 +284,37 @@ func streamAnthropic(resp *http.Response) <-chan Result[Message] {
 			if stopReceived {
 				continue
 			}
+			if event.Val.T1 == "message_start" {
+				var b respMessageStart
+				if err := json.Unmarshal([]byte(event.Val.T2), &b); err != nil {
+					out <- Err[Message](err)
+					for range events {
+						// NOTE: drain the channel to prevent blocking
+					}
+					return
+				}
+				usage = b.Message.Usage
+				setUsage()
+				out <- Ok[Message](msg)
+				continue
+			}
+			if event.Val.T1 == "message_delta" {
+				var b respMessageDelta
+				if err := json.Unmarshal([]byte(event.Val.T2), &b); err != nil {
+					out <- Err[Message](err)
+					for range events {
+						// NOTE: drain the channel to prevent blocking
+					}
+					return
+				}
+				// NOTE: the usage in `message_delta` is cumulative
+				if b.Usage.OutputTokens > 0 {
+					usage.OutputTokens = b.Usage.OutputTokens
+				}
+				setUsage()
+				out <- Ok[Message](msg)
+				continue
+			}
 			if event.Val.T1 == "content_block_start" {
 				var b respContentBlockStart
 				if err := json.Unmarshal([]byte(event.Val.T2), &b); err != nil {
@@ -205,6 +422,17 @@ func streamOpenAI(resp *http.Response) <-chan Result[Message] {
 		Type     string                       `json:"type"`
 		Function respSchema_toolCall_Function `json:"function"`
 	}
+	type respSchema_usage struct {
+		PromptTokens         int64 `json:"prompt_tokens"`
+		CompletionTokens     int64 `json:"completion_tokens"`
+		PromptCacheHitTokens int64 `json:"prompt_cache_hit_tokens"`
+		PromptTokensDetails  struct {
+			CachedTokens int64 `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		CompletionTokensDetails struct {
+			ReasoningTokens int64 `json:"reasoning_tokens"`
+		} `json:"completion_tokens_details"`
+	}
 	type respSchema struct {
 		Error *struct {
 			Code     int             `json:"code"`
@@ -219,6 +447,10 @@ func streamOpenAI(resp *http.Response) <-chan Result[Message] {
 				ToolCalls        []respSchema_toolCall `json:"tool_calls"`
 			} `json:"delta"`
 		} `json:"choices"`
+		Usage *respSchema_usage `json:"usage"`
+		XGroq *struct {
+			Usage *respSchema_usage `json:"usage"`
+		} `json:"x_groq"`
 	}
 	out := make(chan Result[Message])
 	go func() {
@@ -255,6 +487,19 @@ func streamOpenAI(resp *http.Response) <-chan Result[Message] {
 				}
 				return
 			}
+			usage := b.Usage
+			if usage == nil && b.XGroq != nil {
+				usage = b.XGroq.Usage
+			}
+			if usage != nil {
+				msg.SetUsage(AssistantMessageUsage{
+					InputTokens:     usage.PromptTokens,
+					OutputTokens:    usage.CompletionTokens,
+					CachedTokens:    max(usage.PromptTokensDetails.CachedTokens, usage.PromptCacheHitTokens),
+					ReasoningTokens: usage.CompletionTokensDetails.ReasoningTokens,
+				})
+				out <- Ok[Message](msg)
+			}
 			if len(b.Choices) == 0 {
 				continue
 			}
@@ -316,6 +561,119 @@ func streamOpenAI(resp *http.Response) <-chan Result[Message] {
 
 //--------------------------------------------------------------------------------------------------
 
+func streamGoogle(resp *http.Response) <-chan Result[Message] {
+	type respSchema_part struct {
+		Text             string `json:"text"`
+		Thought          bool   `json:"thought"`
+		ThoughtSignature string `json:"thoughtSignature"`
+		FunctionCall     *struct {
+			ID   string          `json:"id"`
+			Name string          `json:"name"`
+			Args json.RawMessage `json:"args"`
+		} `json:"functionCall"`
+	}
+	type respSchema struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+		Candidates []struct {
+			Content struct {
+				Parts []respSchema_part `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		UsageMetadata *struct {
+			PromptTokenCount        int64 `json:"promptTokenCount"`
+			CandidatesTokenCount    int64 `json:"candidatesTokenCount"`
+			CachedContentTokenCount int64 `json:"cachedContentTokenCount"`
+			ThoughtsTokenCount      int64 `json:"thoughtsTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	out := make(chan Result[Message])
+	go func() {
+		defer close(out)
+		events := streamServerSentEvents(resp)
+		msg := NewAssistantMessage("")
+		out <- Ok[Message](msg)
+		finishReceived := false
+		for event := range events {
+			if event.Err != nil {
+				out <- Err[Message](event.Err)
+				return
+			}
+			if finishReceived || event.Val.T1 != "message" {
+				continue
+			}
+			var b respSchema
+			if err := json.Unmarshal(event.Val.T2, &b); err != nil {
+				out <- Err[Message](err)
+				for range events {
+					// NOTE: drain the channel to prevent blocking
+				}
+				return
+			}
+			if b.Error != nil {
+				out <- Err[Message](fmt.Errorf("%s: %s", b.Error.Status, b.Error.Message))
+				for range events {
+					// NOTE: drain the channel to prevent blocking
+				}
+				return
+			}
+			if b.UsageMetadata != nil {
+				msg.SetUsage(AssistantMessageUsage{
+					InputTokens:     b.UsageMetadata.PromptTokenCount,
+					OutputTokens:    b.UsageMetadata.CandidatesTokenCount + b.UsageMetadata.ThoughtsTokenCount,
+					CachedTokens:    b.UsageMetadata.CachedContentTokenCount,
+					ReasoningTokens: b.UsageMetadata.ThoughtsTokenCount,
+				})
+			}
+			if len(b.Candidates) == 0 {
+				continue
+			}
+			for _, part := range b.Candidates[0].Content.Parts {
+				if part.ThoughtSignature != "" {
+					msg.SetTransientMeta("signature", part.ThoughtSignature)
+				}
+				if part.FunctionCall != nil {
+					callID := part.FunctionCall.ID
+					if callID == "" {
+						callID = "call_" + uuid.Must(uuid.NewV7()).String()
+					}
+					args := string(part.FunctionCall.Args)
+					if args == "" || args == "null" {
+						args = "{}"
+					}
+					msg.AppendToolCall(callID, part.FunctionCall.Name, args)
+					out <- Ok[Message](msg)
+					continue
+				}
+				if part.Text == "" {
+					continue
+				}
+				if part.Thought {
+					msg.AppendThinking(part.Text)
+				} else {
+					msg.AppendContent(part.Text)
+				}
+				out <- Ok[Message](msg)
+			}
+			if b.Candidates[0].FinishReason != "" {
+				finishReceived = true
+				out <- Ok[Message](msg)
+			}
+		}
+		if !finishReceived {
+			out <- Err[Message](errors.New("streaming ended without 'finishReason'"))
+			return
+		}
+	}()
+	return out
+}
+
+//--------------------------------------------------------------------------------------------------
+
 func streamServerSentEvents(resp *http.Response) <-chan Result[Tuple[string, []byte]] {
 	out := make(chan Result[Tuple[string, []byte]])
 	go func() {
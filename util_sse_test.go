@@ -0,0 +1,256 @@
+package juttele
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApproveToolCall(t *testing.T) {
+	tools := NewToolCatalog()
+	noop := func(ctx context.Context, args string) (string, error) { return "", nil }
+	tools.Register(NewFuncTool("plain", []byte(`{"name":"plain"}`), noop))
+	tools.Register(NewFuncTool("guarded", []byte(`{"name":"guarded"}`), noop, WithToolApproval()))
+	tests := []struct {
+		name    string
+		tool    string
+		approve func(ctx context.Context, name, args string) (bool, error)
+		wantErr string
+	}{
+		{name: "no approval needed", tool: "plain"},
+		{name: "approval unavailable", tool: "guarded", wantErr: "approval is not available"},
+		{
+			name: "approved", tool: "guarded",
+			approve: func(ctx context.Context, name, args string) (bool, error) { return true, nil },
+		},
+		{
+			name: "rejected", tool: "guarded", wantErr: "the user rejected the call",
+			approve: func(ctx context.Context, name, args string) (bool, error) { return false, nil },
+		},
+		{
+			name: "unanswered", tool: "guarded", wantErr: "did not approve the call to tool \"guarded\" in time",
+			approve: func(ctx context.Context, name, args string) (bool, error) {
+				<-ctx.Done()
+				return false, ctx.Err()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := GenerationConfig{
+				ApproveToolCall:     tt.approve,
+				ToolApprovalTimeout: 10 * time.Millisecond,
+				Tools:               tools,
+			}
+			err := approveToolCall(context.Background(), opts, AssistantMessageToolCall{FuncName: tt.tool})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallTools(t *testing.T) {
+	type span struct{ start, end int }
+	var (
+		mu      sync.Mutex
+		clock   int
+		running int
+		peak    int
+		spans   map[string]*span
+	)
+	// NOTE: each call sleeps for the number of milliseconds in its arguments, which also identify it
+	sleep := func(ctx context.Context, args string) (string, error) {
+		ms, _ := strconv.Atoi(args)
+		mu.Lock()
+		clock++
+		running++
+		peak = max(peak, running)
+		spans[args] = &span{start: clock}
+		mu.Unlock()
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		mu.Lock()
+		clock++
+		running--
+		spans[args].end = clock
+		mu.Unlock()
+		return "done " + args, nil
+	}
+	tools := NewToolCatalog()
+	tools.Register(NewFuncTool("parallel", []byte(`{"name":"parallel"}`), sleep))
+	tools.Register(NewFuncTool("serial", []byte(`{"name":"serial"}`), sleep, WithSerialToolCalls()))
+	tests := []struct {
+		name  string
+		calls []string
+		limit int
+	}{
+		{name: "parallel", calls: []string{"parallel", "parallel", "parallel", "parallel"}, limit: 4},
+		{name: "limited", calls: []string{"parallel", "parallel", "parallel", "parallel", "parallel"}, limit: 2},
+		{name: "serial in the middle", calls: []string{"parallel", "parallel", "serial", "parallel"}, limit: 4},
+		{name: "serial only", calls: []string{"serial", "serial", "serial"}, limit: 4},
+		{name: "serial first and last", calls: []string{"serial", "parallel", "parallel", "serial"}, limit: 4},
+		{name: "unknown tool", calls: []string{"parallel", "missing", "serial"}, limit: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, running, peak, spans = 0, 0, 0, map[string]*span{}
+			calls := make([]AssistantMessageToolCall, len(tt.calls))
+			for idx, name := range tt.calls {
+				// NOTE: earlier calls take longer so that they would finish last without the ordering
+				args := strconv.Itoa(5 * (len(tt.calls) - idx))
+				calls[idx] = AssistantMessageToolCall{CallID: fmt.Sprintf("call_%d", idx), FuncName: name, FuncArgs: args}
+			}
+			opts := GenerationConfig{Tools: tools, ToolConcurrency: tt.limit}
+			var results []*ToolMessage
+			for msg := range callTools(context.Background(), opts, calls) {
+				results = append(results, msg)
+			}
+			if len(results) != len(calls) {
+				t.Fatalf("got %d results, want %d", len(results), len(calls))
+			}
+			for idx, msg := range results {
+				call := calls[idx]
+				if msg.CallID != call.CallID {
+					t.Errorf("result %d is for %s, want %s", idx, msg.CallID, call.CallID)
+				}
+				if call.FuncName == "missing" {
+					if msg.Error == nil {
+						t.Errorf("result %d has no error", idx)
+					}
+					continue
+				}
+				if msg.Result == nil || *msg.Result != "done "+call.FuncArgs {
+					t.Errorf("result %d = %+v", idx, msg)
+				}
+			}
+			if peak > tt.limit {
+				t.Errorf("%d calls ran at once, want at most %d", peak, tt.limit)
+			}
+			for idx, call := range calls {
+				if call.FuncName != "serial" {
+					continue
+				}
+				current := spans[call.FuncArgs]
+				for other, c := range calls {
+					s, ok := spans[c.FuncArgs]
+					if !ok || other == idx {
+						continue
+					}
+					if other < idx && s.end > current.start {
+						t.Errorf("serial call %d started before call %d finished", idx, other)
+					}
+					if other > idx && s.start < current.end {
+						t.Errorf("call %d started before serial call %d finished", other, idx)
+					}
+				}
+			}
+		})
+	}
+}
+
+// fakeToolRequest stands in for a model which calls the echo tool as many times per round as
+// configured, for the given number of rounds or until tool calls are no longer allowed. It records
+// the options of every request.
+func fakeToolRequest(callsPerRound, toolRounds int, requests *[]GenerationConfig) func(GenerationConfig) <-chan Result[Message] {
+	return func(opts GenerationConfig) <-chan Result[Message] {
+		*requests = append(*requests, opts)
+		out := make(chan Result[Message], 1)
+		msg := NewAssistantMessage(fmt.Sprintf("round %d", len(*requests)))
+		if !opts.NoToolCalls && len(*requests) <= toolRounds {
+			for idx := range callsPerRound {
+				msg.ToolCalls = append(msg.ToolCalls, AssistantMessageToolCall{
+					CallID:   fmt.Sprintf("call_%d_%d", len(*requests), idx),
+					FuncName: "echo",
+					FuncArgs: "{}",
+				})
+			}
+		}
+		out <- Ok[Message](msg)
+		close(out)
+		return out
+	}
+}
+
+func TestStreamWithTools(t *testing.T) {
+	tools := NewToolCatalog()
+	tools.Register(NewFuncTool("echo", []byte(`{"name":"echo"}`), func(ctx context.Context, args string) (string, error) {
+		return args, nil
+	}))
+	tests := []struct {
+		name          string
+		callsPerRound int
+		toolRounds    int
+		maxCalls      int
+		maxRounds     int
+		wantRequests  int
+		wantResults   int
+		wantLimited   int
+		wantLimit     string
+	}{
+		{name: "no tool calls", toolRounds: 0, callsPerRound: 1, maxCalls: 10, maxRounds: 10, wantRequests: 1},
+		{name: "within the limits", toolRounds: 2, callsPerRound: 2, maxCalls: 10, maxRounds: 10, wantRequests: 3, wantResults: 4},
+		{name: "round limit", toolRounds: 5, callsPerRound: 1, maxCalls: 10, maxRounds: 2, wantRequests: 3, wantResults: 2, wantLimit: "rounds"},
+		{name: "call limit", toolRounds: 5, callsPerRound: 2, maxCalls: 3, maxRounds: 10, wantRequests: 3, wantResults: 3, wantLimited: 1, wantLimit: "calls"},
+		{
+			name: "call limit in the first round", toolRounds: 5, callsPerRound: 4, maxCalls: 3, maxRounds: 10,
+			wantRequests: 2, wantResults: 3, wantLimited: 1, wantLimit: "calls",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				requests []GenerationConfig
+				history  []Message
+				results  int
+				limited  int
+				last     *AssistantMessage
+			)
+			opts := GenerationConfig{Tools: tools, MaxToolCalls: tt.maxCalls, MaxToolRounds: tt.maxRounds}
+			for i := range streamWithTools(context.Background(), opts, &history, fakeToolRequest(tt.callsPerRound, tt.toolRounds, &requests)) {
+				if i.Err != nil {
+					t.Fatalf("unexpected error: %v", i.Err)
+				}
+				switch msg := i.Val.(type) {
+				case *AssistantMessage:
+					last = msg
+				case *ToolMessage:
+					switch {
+					case msg.Error != nil && msg.Error.Code == toolErrorCodeLimit:
+						limited++
+					case msg.Result != nil:
+						results++
+					default:
+						t.Errorf("unexpected tool message %+v", msg)
+					}
+				}
+			}
+			if len(requests) != tt.wantRequests {
+				t.Fatalf("got %d requests, want %d", len(requests), tt.wantRequests)
+			}
+			if results != tt.wantResults || limited != tt.wantLimited {
+				t.Errorf("got %d results and %d limited calls, want %d and %d", results, limited, tt.wantResults, tt.wantLimited)
+			}
+			// NOTE: only the final request after reaching a limit is made without tool calls
+			for idx, req := range requests {
+				want := tt.wantLimit != "" && idx == len(requests)-1
+				if req.NoToolCalls != want {
+					t.Errorf("request %d: NoToolCalls = %t, want %t", idx, req.NoToolCalls, want)
+				}
+				if req.Tools != tools {
+					t.Errorf("request %d does not declare the tools", idx)
+				}
+			}
+			limit, ok := last.GetPersistedMeta("tool_limit")
+			if limit != tt.wantLimit || ok != (tt.wantLimit != "") {
+				t.Errorf("tool_limit = %q, want %q", limit, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -12,6 +12,11 @@ import (
 	"github.com/markusylisiurunen/juttele/internal/util/jsonrpc"
 )
 
+// rpcClient sends JSON-RPC requests to a client.
+type rpcClient interface {
+	rpc(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
+}
+
 type webSocketProxy struct {
 	mu   sync.Mutex
 	conn *websocket.Conn
@@ -21,6 +26,9 @@ type webSocketProxy struct {
 	pending   map[uint64]chan<- Result[json.RawMessage]
 	pendingMu sync.RWMutex
 
+	handlers   map[string]func(json.RawMessage)
+	handlersMu sync.RWMutex
+
 	closeOnce sync.Once
 	closeChan chan struct{}
 }
@@ -29,6 +37,7 @@ func newWebSocketProxy(conn *websocket.Conn) *webSocketProxy {
 	ws := &webSocketProxy{
 		conn:      conn,
 		pending:   make(map[uint64]chan<- Result[json.RawMessage]),
+		handlers:  make(map[string]func(json.RawMessage)),
 		closeChan: make(chan struct{}),
 	}
 	return ws
@@ -42,14 +51,28 @@ func (ws *webSocketProxy) readLoop() {
 			return
 		default:
 			ws.conn.SetReadDeadline(time.Time{})
-			var res jsonrpc.Response
-			if err := ws.conn.ReadJSON(&res); err != nil {
-				if websocket.IsUnexpectedCloseError(err,
-					websocket.CloseGoingAway, websocket.CloseNormalClosure) {
-					return
+			_, data, err := ws.conn.ReadMessage()
+			if err != nil {
+				// NOTE: a failed connection can't be read from again, so any error means the client is gone
+				return
+			}
+			var notification struct {
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			if err := json.Unmarshal(data, &notification); err == nil && notification.Method != "" {
+				ws.handlersMu.RLock()
+				handler, ok := ws.handlers[notification.Method]
+				ws.handlersMu.RUnlock()
+				if ok {
+					handler(notification.Params)
 				}
 				continue
 			}
+			var res jsonrpc.Response
+			if err := json.Unmarshal(data, &res); err != nil {
+				continue
+			}
 			ws.pendingMu.RLock()
 			ch, ok := ws.pending[res.ID]
 			ws.pendingMu.RUnlock()
@@ -70,6 +93,13 @@ func (ws *webSocketProxy) readLoop() {
 	}
 }
 
+// handle registers a handler for notifications sent by the client.
+func (ws *webSocketProxy) handle(method string, handler func(json.RawMessage)) {
+	ws.handlersMu.Lock()
+	defer ws.handlersMu.Unlock()
+	ws.handlers[method] = handler
+}
+
 func (ws *webSocketProxy) write(v any) error {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()